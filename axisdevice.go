@@ -77,6 +77,10 @@ func (e *Device) AxisDevice(UseDeadZone bool, overrideFlatValue int) (*AxisDevic
 	var ad *AxisDevice
 	var err error
 
+	if err = e.ensureCapabilities(); err != nil {
+		return nil, err
+	}
+
 	if _, ok := e.Capabilities[EV_ABS]; ok {
 
 		ad = CreateAxisDeviceFromAbsInfo(e, e.Absinfos, UseDeadZone, overrideFlatValue)
@@ -0,0 +1,191 @@
+package inputeventsubsystem
+
+import (
+	"syscall"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Backend is the transport behind a Device: the reads, the writes and each ioctl of an evdev node.
+// The default backend use a file descriptor, FakeDevice script a device in memory.
+// The errors are the raw errnos, Device wrap them in a DeviceError
+type Backend interface {
+	// Read is non blocking, it return syscall.EWOULDBLOCK when no event is pending
+	Read(p []byte) (int, error)
+	// Wait until events are pending or the timeout expire, an error mean the backend can not be waited anymore
+	Wait(timeout time.Duration) error
+	Write(p []byte) (int, error)
+	Sync() error
+	Close() error
+
+	Version() (uint32, error)
+	ID() (bus uint16, vendor uint16, product uint16, version uint16, err error)
+	Name() (string, error)
+	Phys() (string, error)
+	Uniq() (string, error)
+	Props() ([]byte, error)
+	Bits(evtype int, max int) ([]byte, error)
+	AbsInfo(code int) (AbsInfo, error)
+	SetAbsInfo(code int, a AbsInfo) error
+	KeyState() ([]byte, error)
+	LedState() ([]byte, error)
+
+	Grab(acquire bool) error
+	Revoke() error
+	SetClockID(clockid int) error
+	Repeat() (delay uint32, period uint32, err error)
+	SetRepeat(delay uint32, period uint32) error
+	ScanCode(key uint16) (uint16, error)
+	Keycode(entry []byte) error
+	SetKeycode(entry []byte) error
+	SetLegacyKeycode(scancode uint32, keycode uint32) error
+	UploadEffect(effect []byte) error
+	EraseEffect(id int16) error
+	EventMask(evtype int, codebits []byte) error
+	SetEventMask(evtype int, codebits []byte) error
+
+	// Ioctl pass a raw request, for the ioctls without a method
+	Ioctl(request uintptr, arg unsafe.Pointer) error
+}
+
+// fdBackend is the Backend of an opened evdev node
+type fdBackend struct {
+	fd int
+}
+
+func (b fdBackend) Read(p []byte) (int, error) {
+	return unix.Read(b.fd, p)
+}
+
+func (b fdBackend) Wait(timeout time.Duration) error {
+	var rFdSet unix.FdSet
+	rFdSet.Set(b.fd)
+
+	t := unix.NsecToTimespec(int64(timeout))
+
+	_, err := unix.Pselect(b.fd+1, &rFdSet, nil, nil, &t, nil)
+	return err
+}
+
+func (b fdBackend) Write(p []byte) (int, error) {
+	return unix.Write(b.fd, p)
+}
+
+func (b fdBackend) Sync() error {
+	return syscall.Fsync(b.fd)
+}
+
+func (b fdBackend) Close() error {
+	return syscall.Close(b.fd)
+}
+
+func (b fdBackend) Version() (uint32, error) {
+	return IoctlInputVersion(b.fd)
+}
+
+func (b fdBackend) ID() (uint16, uint16, uint16, uint16, error) {
+	return IoctlInputID(b.fd)
+}
+
+func (b fdBackend) Name() (string, error) {
+	return IoctlInputName(b.fd)
+}
+
+func (b fdBackend) Phys() (string, error) {
+	return IoctlInputPhys(b.fd)
+}
+
+func (b fdBackend) Uniq() (string, error) {
+	return IoctlInputUniq(b.fd)
+}
+
+func (b fdBackend) Props() ([]byte, error) {
+	return IoctlInputProp(b.fd)
+}
+
+func (b fdBackend) Bits(evtype int, max int) ([]byte, error) {
+	return IoctlInputBit(b.fd, evtype, max)
+}
+
+func (b fdBackend) AbsInfo(code int) (AbsInfo, error) {
+	var a AbsInfo
+
+	absinfobits, err := IoctlInputAbs(b.fd, code)
+	if err == nil {
+		err = a.Unpack(absinfobits)
+	}
+	return a, err
+}
+
+func (b fdBackend) SetAbsInfo(code int, a AbsInfo) error {
+	return IoctlSetInputAbs(b.fd, code, a.Pack())
+}
+
+func (b fdBackend) KeyState() ([]byte, error) {
+	return IoctlInputKey(b.fd)
+}
+
+func (b fdBackend) LedState() ([]byte, error) {
+	return IoctlLeds(b.fd)
+}
+
+func (b fdBackend) Grab(acquire bool) error {
+	return IoctlInputGrab(b.fd, acquire)
+}
+
+func (b fdBackend) Revoke() error {
+	return IoctlInputRevoke(b.fd)
+}
+
+func (b fdBackend) SetClockID(clockid int) error {
+	return IoctlInputClockID(b.fd, clockid)
+}
+
+func (b fdBackend) Repeat() (uint32, uint32, error) {
+	return IoctlGetRepeat(b.fd)
+}
+
+func (b fdBackend) SetRepeat(delay uint32, period uint32) error {
+	return IoctlSetRepeat(b.fd, delay, period)
+}
+
+func (b fdBackend) ScanCode(key uint16) (uint16, error) {
+	return IoctlGetScanCode(b.fd, key)
+}
+
+func (b fdBackend) Keycode(entry []byte) error {
+	return IoctlGetKeycodeV2(b.fd, entry)
+}
+
+func (b fdBackend) SetKeycode(entry []byte) error {
+	return IoctlSetKeycodeV2(b.fd, entry)
+}
+
+func (b fdBackend) SetLegacyKeycode(scancode uint32, keycode uint32) error {
+	return IoctlSetKeycode(b.fd, scancode, keycode)
+}
+
+func (b fdBackend) UploadEffect(effect []byte) error {
+	return IoctlUploadEffect(b.fd, effect)
+}
+
+func (b fdBackend) EraseEffect(id int16) error {
+	return IoctlEraseEffect(b.fd, id)
+}
+
+func (b fdBackend) EventMask(evtype int, codebits []byte) error {
+	return IoctlGetEventMask(b.fd, evtype, codebits)
+}
+
+func (b fdBackend) SetEventMask(evtype int, codebits []byte) error {
+	return IoctlSetEventMask(b.fd, evtype, codebits)
+}
+
+func (b fdBackend) Ioctl(request uintptr, arg unsafe.Pointer) error {
+	if errno := ioctl(uintptr(b.fd), request, arg); errno != 0 {
+		return errno
+	}
+	return nil
+}
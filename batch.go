@@ -0,0 +1,94 @@
+package inputeventsubsystem
+
+import (
+	"sync/atomic"
+)
+
+const batchPoison = 0xa5
+
+var batchDebug int32
+
+// SetBatchDebug enable the debug mode of the batches: released buffers are poisoned and never reused,
+// and any access to a released batch panic. Made for tests, it defeat the pool
+func SetBatchDebug(enabled bool) {
+	if enabled {
+		atomic.StoreInt32(&batchDebug, 1)
+	} else {
+		atomic.StoreInt32(&batchDebug, 0)
+	}
+}
+
+// Batch is a ref counted set of events decoded in place in a pooled buffer.
+// The events are valid until the last Release
+type Batch struct {
+	dev    *Device
+	buf    []byte
+	events []Event
+	refs   int32
+}
+
+func (dev *Device) newBatch(buf []byte, events []Event) *Batch {
+	return &Batch{dev: dev, buf: buf, events: events, refs: 1}
+}
+
+func (b *Batch) Events() []Event {
+	if atomic.LoadInt32(&batchDebug) == 1 && atomic.LoadInt32(&b.refs) <= 0 {
+		panic("inputeventsubsystem: use of a released batch")
+	}
+	return b.events
+}
+
+// Retain add a reference, each Retain must be balanced by a Release
+func (b *Batch) Retain() *Batch {
+	// the count is only raised while alive, a released buffer may be back in the pool already
+	for {
+		refs := atomic.LoadInt32(&b.refs)
+		if refs <= 0 {
+			panic("inputeventsubsystem: retain of a released batch")
+		}
+		if atomic.CompareAndSwapInt32(&b.refs, refs, refs+1) {
+			return b
+		}
+	}
+}
+
+// Release drop a reference, the buffer go back to the pool with the last one
+func (b *Batch) Release() {
+	refs := atomic.AddInt32(&b.refs, -1)
+
+	if refs < 0 {
+		panic("inputeventsubsystem: batch released twice")
+	}
+	if refs > 0 {
+		return
+	}
+
+	if atomic.LoadInt32(&batchDebug) == 1 {
+		for i := range b.buf {
+			b.buf[i] = batchPoison
+		}
+		return
+	}
+
+	b.dev.putBuffer(b.buf)
+}
+
+// ReadBatch start the read loop and return the channel of batches, each batch must be released
+func (dev *Device) ReadBatch() chan *Batch {
+	q := dev.batchQueue()
+
+	go readLoop(dev, q, dev.getBuffer(), true, func(buf []byte, n int) ([]byte, error) {
+		decoded, err := UnsafeUnpackDeviceInputEvents(buf[0:n])
+		if err != nil {
+			return buf, err
+		}
+
+		p := dev.filterUnsafeEvents(decoded)
+		if len(p) == 0 {
+			return buf, nil
+		}
+		q.push(dev.newBatch(buf, p))
+		return dev.getBuffer(), nil
+	})
+	return dev.batchchan
+}
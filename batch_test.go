@@ -0,0 +1,94 @@
+package inputeventsubsystem
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+// nativeSample return the events of data, a 64-bit little endian capture, in the layout of the running arch
+func nativeSample() []byte {
+	capture := EventLayoutTimeval64.WithOrder(binary.LittleEndian)
+	sample := make([]byte, len(data)/capture.Size*NativeEventLayout.Size)
+
+	for i := 0; i < len(data)/capture.Size; i++ {
+		sec, usec, evtype, code, value := capture.Decode(data[i*capture.Size:])
+		NativeEventLayout.Encode(sample[i*NativeEventLayout.Size:], sec, usec, evtype, code, value)
+	}
+	return sample
+}
+
+func unsafeSampleBatch(dev *Device) *Batch {
+	buf := dev.getBuffer()
+	n := copy(buf, nativeSample())
+	return dev.newBatch(buf, eventsOf(buf[:n]))
+}
+
+func TestBatch(t *testing.T) {
+	t.Run("ref count", func(t *testing.T) {
+		b := unsafeSampleBatch(&Device{})
+		b.Retain()
+
+		b.Release()
+		assert.Len(t, b.Events(), 3)
+		assert.Equal(t, uint16(KEY_C), b.Events()[1].Code)
+
+		b.Release()
+		assert.Panics(t, func() { b.Retain() })
+		assert.Equal(t, int32(0), b.refs)
+		assert.Panics(t, func() { b.Release() })
+	})
+
+	t.Run("debug poison", func(t *testing.T) {
+		SetBatchDebug(true)
+		defer SetBatchDebug(false)
+
+		b := unsafeSampleBatch(&Device{})
+		events := b.Events()
+		b.Release()
+
+		assert.Equal(t, uint16(0xa5a5), events[1].Code)
+		assert.Panics(t, func() { b.Events() })
+		assert.Panics(t, func() { b.Release() })
+	})
+
+	t.Run("buffer of resliced events", func(t *testing.T) {
+		for _, size := range []int{64, 5} {
+			dev := &Device{}
+			dev.SetReadBatchSize(size)
+
+			buf := dev.getBuffer()
+			events := eventsOf(buf[0 : 3*deviceinputeventsize])
+
+			assert.Equal(t, size, cap(events))
+			assert.True(t, &buf[0] == &dev.unsafeBufferOf(events)[0])
+			assert.True(t, &buf[0] == &dev.unsafeBufferOf(events[2:])[0])
+			assert.Equal(t, len(buf), len(dev.unsafeBufferOf(events[2:])))
+		}
+	})
+}
+
+func TestReadBatch(t *testing.T) {
+	dev, w := pipeDevice(t)
+	dev.batchchan = make(chan *Batch, 1)
+	defer dev.Close()
+
+	sample := nativeSample()
+	batches := dev.ReadBatch()
+	unix.Write(w, sample)
+
+	select {
+	case b := <-batches:
+		events, err := UnsafeUnpackDeviceInputEvents(alignedCopy(sample, 0))
+		assert.Nil(t, err)
+		assert.Equal(t, events, b.Events())
+		b.Release()
+	case err := <-dev.Error():
+		t.Fatal(err)
+	case <-time.After(time.Second):
+		t.Fatal("no batch")
+	}
+}
@@ -0,0 +1,410 @@
+package inputeventsubsystem
+
+import (
+	"bufio"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sort"
+)
+
+var ErrCaptureFormat = errors.New("invalid capture")
+
+const (
+	captureMagic      = "IEVC"
+	captureVersion    = 1
+	captureCompressed = 0x01
+
+	// bounds checked while reading so a corrupted header can not allocate without limit
+	captureMaxName  = 4096
+	captureMaxCodes = KEY_MAX + 1
+)
+
+// binary capture layout:
+//
+//	magic "IEVC", version, flags (uncompressed)
+//	then, deflated when flags has captureCompressed:
+//	device: name length and bytes, bus, vendor, product, version, properties, codes per type, absinfos
+//	events: timestamp delta to the previous event (ns, the first one from the epoch), type, code, value
+//
+// counts, ids, types and codes are uvarints, code lists are delta encoded, timestamp deltas and values are varints
+
+type captureEncoder struct {
+	w   *bufio.Writer
+	buf [binary.MaxVarintLen64]byte
+	err error // first write error, bufio keep failing after it
+}
+
+func (e *captureEncoder) write(data []byte) {
+	if _, err := e.w.Write(data); err != nil && e.err == nil {
+		e.err = err
+	}
+}
+
+func (e *captureEncoder) uvarint(v uint64) {
+	n := binary.PutUvarint(e.buf[:], v)
+	e.write(e.buf[:n])
+}
+
+func (e *captureEncoder) varint(v int64) {
+	n := binary.PutVarint(e.buf[:], v)
+	e.write(e.buf[:n])
+}
+
+func (e *captureEncoder) codes(codes []int) {
+	sorted := append([]int(nil), codes...)
+	sort.Ints(sorted)
+
+	e.uvarint(uint64(len(sorted)))
+	previous := 0
+	for _, code := range sorted {
+		e.uvarint(uint64(code - previous))
+		previous = code
+	}
+}
+
+func (e *captureEncoder) device(desc EvemuDevice) {
+	name := desc.Name
+	if len(name) > captureMaxName {
+		name = name[:captureMaxName]
+	}
+	e.uvarint(uint64(len(name)))
+	e.write([]byte(name))
+
+	e.uvarint(uint64(desc.Bus))
+	e.uvarint(uint64(desc.VendorID))
+	e.uvarint(uint64(desc.ProductID))
+	e.uvarint(uint64(desc.Version))
+
+	e.codes(desc.Properties)
+
+	var types []int
+	for evtype := range desc.Codes {
+		types = append(types, evtype)
+	}
+	sort.Ints(types)
+
+	e.uvarint(uint64(len(types)))
+	for _, evtype := range types {
+		e.uvarint(uint64(evtype))
+		e.codes(desc.Codes[evtype])
+	}
+
+	var abscodes []int
+	for code := range desc.Absinfos {
+		abscodes = append(abscodes, code)
+	}
+	sort.Ints(abscodes)
+
+	e.uvarint(uint64(len(abscodes)))
+	for _, code := range abscodes {
+		a := desc.Absinfos[code]
+		e.uvarint(uint64(code))
+		e.varint(int64(a.Value))
+		e.varint(int64(a.Minimum))
+		e.varint(int64(a.Maximum))
+		e.varint(int64(a.Fuzz))
+		e.varint(int64(a.Flat))
+		e.varint(int64(a.Resolution))
+	}
+}
+
+// CaptureWriter stream events in the binary capture format
+type CaptureWriter struct {
+	enc      captureEncoder
+	deflate  *flate.Writer
+	previous int64
+}
+
+// NewCaptureWriter write the header describing the device, the events are deflated when compress is set
+func NewCaptureWriter(w io.Writer, desc EvemuDevice, compress bool) (*CaptureWriter, error) {
+
+	var flags byte
+	if compress {
+		flags |= captureCompressed
+	}
+
+	if _, err := w.Write(append([]byte(captureMagic), captureVersion, flags)); err != nil {
+		return nil, err
+	}
+
+	cw := &CaptureWriter{}
+
+	if compress {
+		cw.deflate, _ = flate.NewWriter(w, flate.DefaultCompression)
+		w = cw.deflate
+	}
+
+	cw.enc.w = bufio.NewWriter(w)
+	cw.enc.device(desc)
+
+	return cw, cw.Flush()
+}
+
+func (cw *CaptureWriter) Write(events ...Event) error {
+	for _, ev := range events {
+		ns := ev.Nanoseconds()
+		cw.enc.varint(ns - cw.previous)
+		cw.enc.uvarint(uint64(ev.Type))
+		cw.enc.uvarint(uint64(ev.Code))
+		cw.enc.varint(int64(ev.Value))
+		cw.previous = ns
+	}
+	return cw.enc.err
+}
+
+// Flush push the buffered events to the underlying writer, a compressed stream stay decodable up to this point
+func (cw *CaptureWriter) Flush() error {
+	if err := cw.enc.w.Flush(); err != nil {
+		return err
+	}
+	if cw.deflate != nil {
+		return cw.deflate.Flush()
+	}
+	return nil
+}
+
+// Close flush and terminate the capture, the underlying writer is not closed
+func (cw *CaptureWriter) Close() error {
+	if err := cw.enc.w.Flush(); err != nil {
+		return err
+	}
+	if cw.deflate != nil {
+		return cw.deflate.Close()
+	}
+	return nil
+}
+
+type captureDecoder struct {
+	r *bufio.Reader
+}
+
+// eof is only legit between two events
+func captureError(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return ErrCaptureFormat
+	}
+	return err
+}
+
+func (d *captureDecoder) uvarint(max uint64) (uint64, error) {
+	v, err := binary.ReadUvarint(d.r)
+	if err != nil {
+		return 0, captureError(err)
+	}
+	if v > max {
+		return 0, ErrCaptureFormat
+	}
+	return v, nil
+}
+
+func (d *captureDecoder) int32() (int32, error) {
+	v, err := binary.ReadVarint(d.r)
+	if err != nil {
+		return 0, captureError(err)
+	}
+	if v != int64(int32(v)) {
+		return 0, ErrCaptureFormat
+	}
+	return int32(v), nil
+}
+
+func (d *captureDecoder) codes(max int) ([]int, error) {
+	count, err := d.uvarint(uint64(max))
+	if err != nil {
+		return nil, err
+	}
+
+	codes := make([]int, 0, count)
+	code := 0
+	for i := uint64(0); i < count; i++ {
+		delta, err := d.uvarint(uint64(max))
+		if err != nil {
+			return nil, err
+		}
+		code += int(delta)
+		if code >= max {
+			return nil, ErrCaptureFormat
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+func (d *captureDecoder) device() (EvemuDevice, error) {
+	var desc EvemuDevice
+
+	length, err := d.uvarint(captureMaxName)
+	if err != nil {
+		return desc, err
+	}
+	name := make([]byte, length)
+	if _, err := io.ReadFull(d.r, name); err != nil {
+		return desc, captureError(err)
+	}
+	desc.Name = string(name)
+
+	var ids [4]uint64
+	for i := range ids {
+		if ids[i], err = d.uvarint(0xffff); err != nil {
+			return desc, err
+		}
+	}
+	desc.Bus, desc.VendorID, desc.ProductID, desc.Version = uint16(ids[0]), uint16(ids[1]), uint16(ids[2]), uint16(ids[3])
+
+	if desc.Properties, err = d.codes(INPUT_PROP_MAX + 1); err != nil {
+		return desc, err
+	}
+
+	types, err := d.uvarint(EV_MAX + 1)
+	if err != nil {
+		return desc, err
+	}
+	desc.Codes = make(map[int][]int)
+	for i := uint64(0); i < types; i++ {
+		evtype, err := d.uvarint(EV_MAX)
+		if err != nil {
+			return desc, err
+		}
+		if desc.Codes[int(evtype)], err = d.codes(captureMaxCodes); err != nil {
+			return desc, err
+		}
+	}
+
+	abscount, err := d.uvarint(ABS_MAX + 1)
+	if err != nil {
+		return desc, err
+	}
+	desc.Absinfos = make(map[int]AbsInfo)
+	for i := uint64(0); i < abscount; i++ {
+		code, err := d.uvarint(ABS_MAX)
+		if err != nil {
+			return desc, err
+		}
+		var values [6]int32
+		for j := range values {
+			if values[j], err = d.int32(); err != nil {
+				return desc, err
+			}
+		}
+		desc.Absinfos[int(code)] = AbsInfo{Value: values[0], Minimum: values[1], Maximum: values[2], Fuzz: values[3], Flat: values[4], Resolution: values[5]}
+	}
+
+	return desc, nil
+}
+
+// CaptureReader read a binary capture, the description is read by NewCaptureReader and the events by Next
+type CaptureReader struct {
+	Device   EvemuDevice
+	dec      captureDecoder
+	previous int64
+}
+
+func NewCaptureReader(r io.Reader) (*CaptureReader, error) {
+	var header [len(captureMagic) + 2]byte
+
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, captureError(err)
+	}
+	if string(header[:len(captureMagic)]) != captureMagic || header[len(captureMagic)] != captureVersion {
+		return nil, ErrCaptureFormat
+	}
+
+	if header[len(captureMagic)+1]&captureCompressed != 0 {
+		r = flate.NewReader(r)
+	}
+
+	cr := &CaptureReader{dec: captureDecoder{r: bufio.NewReader(r)}}
+
+	var err error
+	if cr.Device, err = cr.dec.device(); err != nil {
+		return nil, err
+	}
+	return cr, nil
+}
+
+// Next return the next captured event, io.EOF at the end of the capture
+func (cr *CaptureReader) Next() (Event, error) {
+	var ev Event
+
+	delta, err := binary.ReadVarint(cr.dec.r)
+	if err == io.EOF {
+		return ev, io.EOF
+	}
+	if err != nil {
+		return ev, captureError(err)
+	}
+
+	evtype, err := cr.dec.uvarint(EV_MAX)
+	if err != nil {
+		return ev, err
+	}
+	code, err := cr.dec.uvarint(0xffff)
+	if err != nil {
+		return ev, err
+	}
+	value, err := cr.dec.int32()
+	if err != nil {
+		return ev, err
+	}
+
+	cr.previous += delta
+	setTimeval(&ev.Time, cr.previous/1000000000, (cr.previous%1000000000)/1000)
+	ev.Type = uint16(evtype)
+	ev.Code = uint16(code)
+	ev.Value = value
+	return ev, nil
+}
+
+// EvemuToCapture convert an evemu-record file to a binary capture
+func EvemuToCapture(dst io.Writer, src io.Reader, compress bool) error {
+	er, err := NewEvemuReader(src)
+	if err != nil {
+		return err
+	}
+
+	cw, err := NewCaptureWriter(dst, er.Device, compress)
+	if err != nil {
+		return err
+	}
+
+	for {
+		ev, err := er.Next()
+		if err == io.EOF {
+			return cw.Close()
+		}
+		if err != nil {
+			return err
+		}
+		if err := cw.Write(ev); err != nil {
+			return err
+		}
+	}
+}
+
+// CaptureToEvemu convert a binary capture to an evemu-record file, the timestamps become relative to the first event
+func CaptureToEvemu(dst io.Writer, src io.Reader) error {
+	cr, err := NewCaptureReader(src)
+	if err != nil {
+		return err
+	}
+
+	rec, err := NewEvemuRecorder(dst, cr.Device)
+	if err != nil {
+		return err
+	}
+
+	for {
+		ev, err := cr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := rec.Record(ev); err != nil {
+			return err
+		}
+	}
+}
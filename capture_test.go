@@ -0,0 +1,106 @@
+package inputeventsubsystem
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func readCapture(t *testing.T, r io.Reader) (EvemuDevice, []Event) {
+	cr, err := NewCaptureReader(r)
+	assert.Nil(t, err)
+
+	var events []Event
+	for {
+		ev, err := cr.Next()
+		if err == io.EOF {
+			return cr.Device, events
+		}
+		assert.Nil(t, err)
+		events = append(events, ev)
+	}
+}
+
+func TestCaptureRoundTrip(t *testing.T) {
+	desc, events, err := ParseEvemu(strings.NewReader(evemuSample))
+	assert.Nil(t, err)
+
+	// absolute timestamps survive the delta encoding
+	for i := range events {
+		setTimeval(&events[i].Time, 1700000000+int64(events[i].Time.Sec), int64(events[i].Time.Usec))
+	}
+
+	for _, compress := range []bool{false, true} {
+		var buf bytes.Buffer
+
+		cw, err := NewCaptureWriter(&buf, desc, compress)
+		assert.Nil(t, err)
+		assert.Nil(t, cw.Write(events...))
+		assert.Nil(t, cw.Close())
+
+		desc2, events2 := readCapture(t, &buf)
+		assert.Equal(t, desc, desc2)
+		assert.Equal(t, events, events2)
+	}
+}
+
+func TestCaptureSize(t *testing.T) {
+	var evemu, capture bytes.Buffer
+
+	desc := EvemuDevice{Name: "mouse", Codes: map[int][]int{EV_SYN: {}, EV_REL: {REL_X, REL_Y}}}
+	rec, _ := NewEvemuRecorder(&evemu, desc)
+	cw, _ := NewCaptureWriter(&capture, desc, false)
+
+	var ev Event
+	for i := 0; i < 1000; i++ {
+		setTimeval(&ev.Time, 1700000000, int64(i*8000))
+		ev.Type, ev.Code, ev.Value = EV_REL, REL_X, int32(i%7-3)
+		rec.Record(ev)
+		cw.Write(ev)
+		ev.Type, ev.Code, ev.Value = EV_SYN, SYN_REPORT, 0
+		rec.Record(ev)
+		cw.Write(ev)
+	}
+	cw.Close()
+
+	// 8ms deltas fit in 3 bytes, an event take 6 bytes
+	assert.Less(t, capture.Len(), 6*2000+200)
+	assert.Less(t, capture.Len()*5, evemu.Len())
+}
+
+func TestCaptureEvemuConversion(t *testing.T) {
+	var capture, evemu bytes.Buffer
+
+	assert.Nil(t, EvemuToCapture(&capture, strings.NewReader(evemuSample), true))
+	assert.Nil(t, CaptureToEvemu(&evemu, &capture))
+
+	desc, events, _ := ParseEvemu(strings.NewReader(evemuSample))
+	desc2, events2, err := ParseEvemu(&evemu)
+	assert.Nil(t, err)
+	assert.Equal(t, desc, desc2)
+	assert.Equal(t, events, events2)
+}
+
+func TestCaptureInvalid(t *testing.T) {
+	_, err := NewCaptureReader(strings.NewReader("EVEMU"))
+	assert.True(t, errors.Is(err, ErrCaptureFormat))
+
+	var buf bytes.Buffer
+	cw, _ := NewCaptureWriter(&buf, EvemuDevice{Name: "pad"}, false)
+	cw.Write(Event{Type: EV_KEY, Code: BTN_SOUTH, Value: 1})
+	cw.Close()
+
+	// truncated inside the last event
+	cr, err := NewCaptureReader(bytes.NewReader(buf.Bytes()[:buf.Len()-1]))
+	assert.Nil(t, err)
+	_, err = cr.Next()
+	assert.True(t, errors.Is(err, ErrCaptureFormat))
+
+	// name length over the limit
+	_, err = NewCaptureReader(bytes.NewReader(append([]byte("IEVC\x01\x00"), 0xff, 0xff, 0x03)))
+	assert.True(t, errors.Is(err, ErrCaptureFormat))
+}
@@ -0,0 +1,22 @@
+package inputeventsubsystem
+
+import "time"
+
+// Clock abstract the time source so timed helpers can be driven deterministically in tests
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// SystemClock is the default Clock backed by the time package
+var SystemClock Clock = systemClock{}
@@ -593,10 +593,11 @@ var BtnCodesString = map[uint16]string{
 }
 
 var SynCodesString = map[uint16]string{
-	SYN_REPORT:    "SYN_REPORT",
-	SYN_CONFIG:    "SYN_CONFIG",
-	SYN_MT_REPORT: "SYN_MT_REPORT",
-	SYN_DROPPED:   "SYN_DROPPED",
+	SYN_REPORT:      "SYN_REPORT",
+	SYN_CONFIG:      "SYN_CONFIG",
+	SYN_MT_REPORT:   "SYN_MT_REPORT",
+	SYN_DROPPED:     "SYN_DROPPED",
+	SYN_RECONNECTED: "SYN_RECONNECTED",
 }
 
 var RelCodesString = map[uint16]string{
@@ -629,6 +630,26 @@ const (
 	EV_MAX       = 0x1f
 )
 
+var LedCodesString = map[uint16]string{
+	LED_NUML:     "LED_NUML",
+	LED_CAPSL:    "LED_CAPSL",
+	LED_SCROLLL:  "LED_SCROLLL",
+	LED_COMPOSE:  "LED_COMPOSE",
+	LED_KANA:     "LED_KANA",
+	LED_SLEEP:    "LED_SLEEP",
+	LED_SUSPEND:  "LED_SUSPEND",
+	LED_MUTE:     "LED_MUTE",
+	LED_MISC:     "LED_MISC",
+	LED_MAIL:     "LED_MAIL",
+	LED_CHARGING: "LED_CHARGING",
+	LED_MAX:      "LED_MAX",
+}
+
+var RepCodesString = map[uint16]string{
+	REP_DELAY:  "REP_DELAY",
+	REP_PERIOD: "REP_PERIOD",
+}
+
 var evtypeString = map[int]string{
 	EV_SYN: "EV_SYN",
 	EV_KEY: "EV_KEY",
@@ -1266,4 +1287,29 @@ const (
 	ABS_MT_TOOL_X      = 0x3c
 	ABS_MT_TOOL_Y      = 0x3d
 	ABS_MAX            = 0x3f
+	LED_NUML           = 0x00
+	LED_CAPSL          = 0x01
+	LED_SCROLLL        = 0x02
+	LED_COMPOSE        = 0x03
+	LED_KANA           = 0x04
+	LED_SLEEP          = 0x05
+	LED_SUSPEND        = 0x06
+	LED_MUTE           = 0x07
+	LED_MISC           = 0x08
+	LED_MAIL           = 0x09
+	LED_CHARGING       = 0x0a
+	REP_DELAY          = 0x00
+	REP_PERIOD         = 0x01
+	REP_MAX            = 0x01
+)
+
+const (
+	INPUT_PROP_POINTER        = 0x00
+	INPUT_PROP_DIRECT         = 0x01
+	INPUT_PROP_BUTTONPAD      = 0x02
+	INPUT_PROP_SEMI_MT        = 0x03
+	INPUT_PROP_TOPBUTTONPAD   = 0x04
+	INPUT_PROP_POINTING_STICK = 0x05
+	INPUT_PROP_ACCELEROMETER  = 0x06
+	INPUT_PROP_MAX            = 0x1f
 )
@@ -1,16 +1,14 @@
 package inputeventsubsystem
 
 import (
-	"bytes"
 	"encoding/binary"
 	"fmt"
 	"os"
+	"sync"
 	"sync/atomic"
 	"syscall"
 	"time"
 	"unsafe"
-
-	"golang.org/x/sys/unix"
 )
 
 type AbsInfo struct {
@@ -22,23 +20,47 @@ type AbsInfo struct {
 	Resolution int32
 }
 
-func (a *AbsInfo) Unpack(data []byte) {
+func (a *AbsInfo) Unpack(data []byte) error {
+	return a.UnpackOrder(data, binary.NativeEndian)
+}
+
+// UnpackOrder decode a struct input_absinfo encoded with the given byte order, data hold at least sizeofAbsInfo bytes
+func (a *AbsInfo) UnpackOrder(data []byte, order binary.ByteOrder) error {
+	if len(data) < sizeofAbsInfo {
+		return fmt.Errorf("%w: %d bytes", ErrAbsInfoLength, len(data))
+	}
 
 	//very important , we used basic read . Read with a struct use reflection and are very slow
 
-	buf := bytes.NewBuffer(data)
-	binary.Read(buf, binary.LittleEndian, &a.Value)
-	binary.Read(buf, binary.LittleEndian, &a.Minimum)
-	binary.Read(buf, binary.LittleEndian, &a.Maximum)
-	binary.Read(buf, binary.LittleEndian, &a.Fuzz)
-	binary.Read(buf, binary.LittleEndian, &a.Flat)
-	binary.Read(buf, binary.LittleEndian, &a.Resolution)
+	a.Value = int32(order.Uint32(data[0:]))
+	a.Minimum = int32(order.Uint32(data[4:]))
+	a.Maximum = int32(order.Uint32(data[8:]))
+	a.Fuzz = int32(order.Uint32(data[12:]))
+	a.Flat = int32(order.Uint32(data[16:]))
+	a.Resolution = int32(order.Uint32(data[20:]))
+	return nil
+}
+
+func (a *AbsInfo) Pack() []byte {
+	return a.PackOrder(binary.NativeEndian)
+}
+
+func (a *AbsInfo) PackOrder(order binary.ByteOrder) []byte {
+	var data []byte = make([]byte, sizeofAbsInfo)
+
+	order.PutUint32(data[0:], uint32(a.Value))
+	order.PutUint32(data[4:], uint32(a.Minimum))
+	order.PutUint32(data[8:], uint32(a.Maximum))
+	order.PutUint32(data[12:], uint32(a.Fuzz))
+	order.PutUint32(data[16:], uint32(a.Flat))
+	order.PutUint32(data[20:], uint32(a.Resolution))
+	return data
 }
 
 type Device struct {
 	Fn              string   // path to input device (devnode)
 	File            *os.File // an open file handle to the input device
-	fd              int
+	backend         Backend
 	DriverVersion   uint32
 	bus             uint16
 	VendorID        uint16
@@ -46,12 +68,25 @@ type Device struct {
 	Version         uint16
 	Name            string
 	Phy             string
+	Uniq            string
+	Properties      []int // INPUT_PROP_* of the device
 	Capabilities    map[int]map[int]string
 	Absinfos        map[int]AbsInfo
 	eventchan       chan []*Event
 	unsafeeventchan chan []Event
+	batchchan       chan *Batch
 	errorchan       chan error
 	stopped         int32
+	revoked         int32
+	clockid         int
+	batchsize       int
+	policy          OverflowPolicy
+	buffers         sync.Pool
+	dropped         atomic.Uint64
+	coalesced       atomic.Uint64
+	eventmasks      map[int][]int
+	probed          bool
+	softmask        atomic.Value
 }
 
 func (e *Device) String() string {
@@ -60,47 +95,102 @@ func (e *Device) String() string {
 
 // Open an evdev input device.
 func Open(devnode string, buffersize int) (*Device, error) {
+	return OpenWithOptions(devnode, WithBufferSize(buffersize))
+}
+
+// OpenWithOptions open an evdev input device configured by the options
+func OpenWithOptions(devnode string, options ...OpenOption) (*Device, error) {
+
+	config := defaultOpenConfig()
+	for _, option := range options {
+		option(&config)
+	}
 
 	var dev Device
 	dev.Fn = devnode
 
-	f, err := unix.Open(dev.Fn, syscall.O_CLOEXEC|syscall.O_NONBLOCK|syscall.O_RDWR, 0666)
+	flags := syscall.O_CLOEXEC | syscall.O_NONBLOCK | syscall.O_RDWR
+	if config.readonly {
+		flags = syscall.O_CLOEXEC | syscall.O_NONBLOCK | syscall.O_RDONLY
+	}
+
+	f, err := config.opener(dev.Fn, flags)
 
 	if err != nil {
-		return nil, err
+		return nil, dev.wrapError(OpOpen, nil, err)
 	}
 
-	dev.fd = f
+	return openBackend(devnode, fdBackend{fd: f}, config)
+}
 
-	if dev.DriverVersion, err = IoctlInputVersion(dev.fd); err != nil {
+// OpenBackend open a device served by backend, like a FakeDevice. path only name the device in the errors
+func OpenBackend(path string, backend Backend, options ...OpenOption) (*Device, error) {
 
-		dev.DriverVersion = 0
-		defer syscall.Close(dev.fd)
-		return nil, ErrDriverVersion
+	config := defaultOpenConfig()
+	for _, option := range options {
+		option(&config)
 	}
 
-	if dev.bus, dev.VendorID, dev.ProductID, dev.Version, err = IoctlInputID(dev.fd); err != nil {
-		defer syscall.Close(dev.fd)
-		return nil, ErrDeviceInformation
-	}
+	return openBackend(path, backend, config)
+}
 
-	dev.eventchan = make(chan []*Event, buffersize)
-	dev.unsafeeventchan = make(chan []Event, buffersize)
-	dev.errorchan = make(chan error)
+func openBackend(path string, backend Backend, config openConfig) (*Device, error) {
+	var err error
 
-	dev.Name, _ = IoctlInputName(dev.fd)
-	dev.Phy, _ = IoctlInputPhys(dev.fd)
+	var dev Device
+	dev.Fn = path
+	dev.backend = backend
 
-	var evbits []byte
+	if dev.DriverVersion, err = dev.backend.Version(); err != nil {
 
-	if evbits, err = IoctlInputBit(dev.fd, 0, EV_MAX); err != nil {
-		defer syscall.Close(dev.fd)
-		return nil, ErrEvBits
+		dev.DriverVersion = 0
+		defer dev.backend.Close()
+		return nil, dev.wrapError(OpIdentify, ErrDriverVersion, err)
+	}
 
+	if dev.bus, dev.VendorID, dev.ProductID, dev.Version, err = dev.backend.ID(); err != nil {
+		defer dev.backend.Close()
+		return nil, dev.wrapError(OpIdentify, ErrDeviceInformation, err)
 	}
 
+	dev.eventchan = make(chan []*Event, config.buffersize)
+	dev.unsafeeventchan = make(chan []Event, config.buffersize)
+	dev.batchchan = make(chan *Batch, config.buffersize)
+	dev.errorchan = make(chan error)
+	dev.eventmasks = make(map[int][]int)
 	dev.Capabilities = make(map[int]map[int]string)
 	dev.Absinfos = make(map[int]AbsInfo)
+	dev.batchsize = config.batchsize
+	dev.policy = config.policy
+
+	dev.Name, _ = dev.backend.Name()
+	dev.Phy, _ = dev.backend.Phys()
+	dev.Uniq, _ = dev.backend.Uniq()
+
+	if !config.lazyprobe {
+		if err = dev.ProbeCapabilities(); err != nil {
+			defer dev.backend.Close()
+			return nil, err
+		}
+	}
+
+	if err = config.apply(&dev); err != nil {
+		defer dev.backend.Close()
+		return nil, err
+	}
+
+	return &dev, nil
+}
+
+// ProbeCapabilities read the event types, codes and absinfos of the device. Open call it unless WithLazyProbe is given
+func (dev *Device) ProbeCapabilities() error {
+	var err error
+
+	var evbits []byte
+
+	if evbits, err = dev.backend.Bits(0, EV_MAX); err != nil {
+		return dev.wrapError(OpProbe, ErrEvBits, err)
+	}
 
 	for evtype := 0; evtype < EV_MAX; evtype++ {
 		if evbits[evtype/8]&(1<<uint(evtype%8)) != 0 {
@@ -111,7 +201,7 @@ func Open(devnode string, buffersize int) (*Device, error) {
 
 				var codebits []byte
 
-				if codebits, err = IoctlInputBit(dev.fd, evtype, KEY_MAX); err == nil {
+				if codebits, err = dev.backend.Bits(evtype, KEY_MAX); err == nil {
 
 					for evcode := 0; evcode < KEY_MAX; evcode++ {
 						if codebits[evcode/8]&(1<<uint(evcode%8)) != 0 {
@@ -124,10 +214,39 @@ func Open(devnode string, buffersize int) (*Device, error) {
 
 			}
 
+			if evtype == EV_LED {
+
+				var ledbits []byte
+
+				if ledbits, err = dev.backend.Bits(evtype, LED_MAX); err == nil {
+
+					for ledcode := 0; ledcode <= LED_MAX; ledcode++ {
+						if ledbits[ledcode/8]&(1<<uint(ledcode%8)) != 0 {
+							dev.Capabilities[evtype][ledcode] = fmt.Sprintf("0x%x", ledcode)
+						}
+					}
+
+				}
+
+			}
+
+			if evtype == EV_REL || evtype == EV_MSC || evtype == EV_SW || evtype == EV_SND || evtype == EV_FF {
+
+				count := eventMaskCodeCount(evtype)
+
+				var codebits []byte
+				if codebits, err = dev.backend.Bits(evtype, count-1); err == nil {
+					for _, evcode := range bitsToCodes(codebits, count) {
+						dev.Capabilities[evtype][evcode] = fmt.Sprintf("0x%x", evcode)
+					}
+				}
+
+			}
+
 			if evtype == EV_ABS {
 
 				var absbits []byte
-				if absbits, err = IoctlInputBit(dev.fd, evtype, ABS_MAX); err == nil {
+				if absbits, err = dev.backend.Bits(evtype, ABS_MAX); err == nil {
 
 					for abscode := 0; abscode < ABS_MAX; abscode++ {
 						if absbits[abscode/8]&(1<<uint(abscode%8)) != 0 {
@@ -137,14 +256,8 @@ func Open(devnode string, buffersize int) (*Device, error) {
 							//hat not have absinfo
 							if abscode < ABS_HAT0X || abscode > ABS_HAT3Y {
 
-								var absinfobits []byte
-
-								if absinfobits, err = IoctlInputAbs(dev.fd, abscode); err == nil {
-									var a AbsInfo
-
-									a.Unpack(absinfobits[:])
+								if a, err := dev.backend.AbsInfo(abscode); err == nil {
 									dev.Absinfos[abscode] = a
-
 								}
 
 							}
@@ -160,7 +273,19 @@ func Open(devnode string, buffersize int) (*Device, error) {
 		}
 	}
 
-	return &dev, nil
+	if propbits, err := dev.backend.Props(); err == nil {
+		dev.Properties = bitsToCodes(propbits, INPUT_PROP_MAX+1)
+	}
+
+	dev.probed = true
+	return nil
+}
+
+func (dev *Device) ensureCapabilities() error {
+	if dev.probed {
+		return nil
+	}
+	return dev.ProbeCapabilities()
 }
 
 func (dev *Device) Error() <-chan error {
@@ -168,97 +293,150 @@ func (dev *Device) Error() <-chan error {
 	return dev.errorchan
 }
 
-func (dev *Device) UnsafeRead() chan []Event {
-
-	go func() {
-		//var events [deviceinputeventsize * 64]byte
-		events := eventPoolUnsafe.Get().(*[deviceinputeventsize * 64]byte)
-		for {
+// sendError report the error ending a read loop, it is dropped if nobody listen on Error
+func (dev *Device) sendError(err error) {
+	select {
+	case dev.errorchan <- err:
 
-			rFdSet := &unix.FdSet{}
-			fd := int(dev.fd)
-			rFdSet.Set(fd)
+	case <-time.After(time.Duration(100) * time.Millisecond):
+	}
+}
 
-			t := unix.Timespec{Sec: 1 /*sec*/, Nsec: 0 /*usec*/}
+// readLoop run the read loop shared by Read, UnsafeRead and ReadBatch until Close or an error. push decode the
+// n bytes read in buf, queue them and return the buffer of the next read. When pooled, the buffer is given back
+// to the pool once the loop end
+func readLoop[T any](dev *Device, q *readQueue[T], buf []byte, pooled bool, push func(buf []byte, n int) ([]byte, error)) {
+	release := func() {
+		if pooled {
+			dev.putBuffer(buf)
+		}
+	}
 
-			if _, err := unix.Pselect(fd+1, rFdSet, nil, nil, &t, nil); err == nil {
+	for {
 
-				if n, err := unix.Read(fd, events[:]); err == nil {
-					p := UnsafeUnpackDeviceInputEvents(events[0:n])
-					dev.unsafeeventchan <- p
-					events = eventPoolUnsafe.Get().(*[deviceinputeventsize * 64]byte)
+		q.flush()
 
-				} else {
+		if err := dev.backend.Wait(q.timeout()); err == nil {
 
-					if err != syscall.EWOULDBLOCK {
-						select {
-						case dev.errorchan <- err:
+			if n, err := dev.backend.Read(buf); err == nil {
+				next, err := push(buf, n)
+				if err != nil {
+					release()
+					dev.sendError(dev.wrapError(OpRead, nil, err))
+					return
+				}
+				buf = next
 
-						case <-time.After(time.Duration(100) * time.Millisecond):
-						}
-						return
-					}
+			} else {
 
+				if err != syscall.EWOULDBLOCK {
+					release()
+					dev.sendError(dev.readError(err))
+					return
 				}
 
 			}
 
-			if atomic.LoadInt32(&dev.stopped) == 1 {
-				return
-			}
+		}
 
+		if atomic.LoadInt32(&dev.stopped) == 1 {
+			release()
+			return
 		}
 
-	}()
-	return dev.unsafeeventchan
+	}
 }
 
-func (dev *Device) Read() chan []*Event {
-
-	go func() {
-		var events [deviceinputeventsize * 64]byte
+// UnsafeRead start the read loop, the slices alias pooled buffers and must be given back with UnsafeReadDone.
+// Prefer ReadBatch that track the ownership of the buffers
+func (dev *Device) UnsafeRead() chan []Event {
+	q := dev.unsafeEventsQueue()
 
-		for {
+	go readLoop(dev, q, dev.getBuffer(), true, func(buf []byte, n int) ([]byte, error) {
+		decoded, err := UnsafeUnpackDeviceInputEvents(buf[0:n])
+		if err != nil {
+			return buf, err
+		}
 
-			rFdSet := &unix.FdSet{}
-			fd := int(dev.fd)
-			rFdSet.Set(fd)
+		p := dev.filterUnsafeEvents(decoded)
+		if len(p) == 0 {
+			return buf, nil
+		}
+		q.push(p)
+		return dev.getBuffer(), nil
+	})
+	return dev.unsafeeventchan
+}
 
-			t := unix.Timespec{Sec: 1 /*sec*/, Nsec: 0 /*usec*/}
+func (dev *Device) Read() chan []*Event {
+	q := dev.eventsQueue()
 
-			if _, err := unix.Pselect(fd+1, rFdSet, nil, nil, &t, nil); err == nil {
+	go readLoop(dev, q, make([]byte, dev.ReadBatchSize()*deviceinputeventsize), false, func(buf []byte, n int) ([]byte, error) {
+		decoded, err := UnpackDeviceInputEvents(buf[0:n])
+		if err != nil {
+			return buf, err
+		}
 
-				if n, err := unix.Read(fd, events[:]); err == nil {
-					p := UnpackDeviceInputEvents(events[0:n])
-					dev.eventchan <- p
+		if p := dev.filterEvents(decoded); len(p) > 0 {
+			q.push(p)
+		}
+		return buf, nil
+	})
+	return dev.eventchan
+}
 
-				} else {
+func (dev *Device) Grab(state bool) error {
+	return dev.wrapError(OpIoctl, nil, dev.backend.Grab(state))
+}
 
-					if err != syscall.EWOULDBLOCK {
-						select {
-						case dev.errorchan <- err:
+// Revoke the access to the device for everyone holding this file descriptor, only Close is allowed afterward
+func (dev *Device) Revoke() error {
+	if err := dev.backend.Revoke(); err != nil {
+		return dev.wrapError(OpIoctl, nil, err)
+	}
+	atomic.StoreInt32(&dev.revoked, 1)
+	return nil
+}
 
-						case <-time.After(time.Duration(100) * time.Millisecond):
-						}
-						return
-					}
+// readError wrap the read error, ENODEV is reported as ErrRevoked when the fd was revoked by Revoke and as
+// ErrDeviceGone otherwise. A revoke by another process holding the fd can not be told from an unplug,
+// the node is removed asynchronously by devtmpfs and may still exist
+func (dev *Device) readError(err error) error {
+	if err == syscall.ENODEV && atomic.LoadInt32(&dev.revoked) == 1 {
+		return dev.wrapError(OpRead, ErrRevoked, err)
+	}
 
-				}
+	return dev.wrapError(OpRead, nil, err)
+}
 
-			}
+// SetClock select the clock used by the kernel to timestamp the events (CLOCK_REALTIME, CLOCK_MONOTONIC or CLOCK_BOOTTIME)
+func (dev *Device) SetClock(clockid int) error {
+	switch clockid {
+	case CLOCK_REALTIME, CLOCK_MONOTONIC, CLOCK_BOOTTIME:
+	default:
+		return ErrClockID
+	}
 
-			if atomic.LoadInt32(&dev.stopped) == 1 {
-				return
-			}
+	if err := dev.backend.SetClockID(clockid); err != nil {
+		return dev.wrapError(OpIoctl, nil, err)
+	}
+	dev.clockid = clockid
+	return nil
+}
 
-		}
+// ClockID return the clock used to timestamp the events of the device
+func (dev *Device) ClockID() int {
+	return dev.clockid
+}
 
-	}()
-	return dev.eventchan
+// EventTimestamp return the wall time of an event read from the device, mapped with the clock of the device
+func (dev *Device) EventTimestamp(ev Event) time.Time {
+	return ev.Timestamp(dev.clockid)
 }
 
-func (dev *Device) Grab(state bool) error {
-	return IoctlInputGrab(dev.fd, state)
+// EventLatency return the time elapsed since an event read from the device occurred
+func (dev *Device) EventLatency(ev Event) time.Duration {
+	return ev.Latency(dev.clockid)
 }
 
 func (dev *Device) StopRead() {
@@ -272,13 +450,14 @@ func (dev *Device) ReadDone(events []*Event) {
 	}
 }
 
+// UnsafeReadDone give back the buffer of a slice returned by UnsafeRead. Prefer ReadBatch and Batch.Release
 func (dev *Device) UnsafeReadDone(events []Event) {
-	eventPoolUnsafe.Put((*[deviceinputeventsize * 64]byte)(unsafe.Pointer(&events[0])))
+	dev.putBuffer(dev.unsafeBufferOf(events))
 }
 
 func (dev *Device) Close() error {
 	atomic.StoreInt32(&dev.stopped, 1)
-	return syscall.Close(dev.fd)
+	return dev.backend.Close()
 }
 
 func (dev *Device) KeysState() ([]byte, error) {
@@ -286,11 +465,11 @@ func (dev *Device) KeysState() ([]byte, error) {
 	var keybits []byte
 	var err error
 
-	if keybits, err = IoctlInputKey(dev.fd); err == nil {
+	if keybits, err = dev.backend.KeyState(); err == nil {
 		return keybits, nil
 	}
 
-	return nil, ErrEvBits
+	return nil, dev.wrapError(OpIoctl, ErrEvBits, err)
 
 }
 
@@ -299,47 +478,57 @@ func (dev *Device) LesdsState() ([]byte, error) {
 	var ledsbits []byte
 	var err error
 
-	if ledsbits, err = IoctlLeds(dev.fd); err == nil {
+	if ledsbits, err = dev.backend.LedState(); err == nil {
 		return ledsbits, nil
 	}
 
-	return nil, ErrEvBits
+	return nil, dev.wrapError(OpIoctl, ErrEvBits, err)
 
 }
 
 func (dev *Device) AbsState(abscode int) (AbsInfo, error) {
-	var a AbsInfo
-
-	var absinfobits []byte
-	var err error
-
-	if absinfobits, err = IoctlInputAbs(dev.fd, abscode); err == nil {
-		var a AbsInfo
-
-		a.Unpack(absinfobits[:])
+	a, err := dev.backend.AbsInfo(abscode)
+	if err == nil {
 		dev.Absinfos[abscode] = a
 		return a, nil
 	}
 
-	return a, ErrAbsBits
+	return a, dev.wrapError(OpIoctl, ErrAbsBits, err)
 
 }
-func (dev *Device) IoCtl(name uintptr, data unsafe.Pointer) error {
-	var err error
-	if errno := ioctl(uintptr(dev.fd), name, data); errno != 0 {
-		err = errno
+
+// SetAbsInfo override the axis min/max/fuzz/flat/resolution in the kernel
+func (dev *Device) SetAbsInfo(abscode int, a AbsInfo) error {
+
+	if err := dev.backend.SetAbsInfo(abscode, a); err != nil {
+		return dev.wrapError(OpIoctl, nil, err)
 	}
-	return err
+
+	dev.Absinfos[abscode] = a
+	return nil
+}
+
+func (dev *Device) IoCtl(name uintptr, data unsafe.Pointer) error {
+	return dev.wrapError(OpIoctl, nil, dev.backend.Ioctl(name, data))
 }
 
 func (dev *Device) Write(data []byte) (int, error) {
-	return unix.Write(dev.fd, data)
+	n, err := dev.backend.Write(data)
+	return n, dev.wrapError(OpWrite, nil, err)
+}
+
+func (dev *Device) WriteEvent(evtype uint16, code uint16, value int32) error {
+	ev := Event{Type: evtype, Code: code, Value: value}
+
+	_, err := dev.Write((*[deviceinputeventsize]byte)(unsafe.Pointer(&ev))[:])
+	return err
 }
 
 func (dev *Device) Sync() error {
-	return syscall.Fsync(dev.fd)
+	return dev.wrapError(OpIoctl, nil, dev.backend.Sync())
 }
 
 func (dev *Device) GetScanCode(code uint16) (uint16, error) {
-	return IoctlGetScanCode(dev.fd, code)
+	scancode, err := dev.backend.ScanCode(code)
+	return scancode, dev.wrapError(OpIoctl, nil, err)
 }
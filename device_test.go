@@ -1,7 +1,62 @@
 package inputeventsubsystem
 
-import "testing"
+import (
+	"encoding/binary"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
 
 func TestMain(m *testing.M) {
 	m.Run()
 }
+
+func TestAbsInfoPack(t *testing.T) {
+	a := AbsInfo{Value: 12, Minimum: -32768, Maximum: 32767, Fuzz: 16, Flat: 128, Resolution: 3}
+
+	data := a.Pack()
+	assert.Len(t, data, 24)
+	assert.Equal(t, uint32(0xffff8000), binary.NativeEndian.Uint32(data[4:]))
+
+	var a2 AbsInfo
+	assert.Nil(t, a2.Unpack(data))
+	assert.Equal(t, a, a2)
+}
+
+func FuzzAbsInfoUnpack(f *testing.F) {
+	a := AbsInfo{Value: 12, Minimum: -32768, Maximum: 32767, Fuzz: 16, Flat: 128, Resolution: 3}
+	f.Add(a.Pack())
+	f.Add(a.Pack()[:sizeofAbsInfo-1])
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var a AbsInfo
+
+		err := a.Unpack(data)
+		if len(data) < sizeofAbsInfo {
+			assert.ErrorIs(t, err, ErrAbsInfoLength)
+			return
+		}
+
+		assert.Nil(t, err)
+		assert.Equal(t, data[:sizeofAbsInfo], a.Pack())
+	})
+}
+
+func TestReadError(t *testing.T) {
+	dev := Device{Fn: t.TempDir()}
+
+	// an unplug often fail the read before devtmpfs remove the node
+	assert.ErrorIs(t, dev.readError(syscall.ENODEV), ErrDeviceGone)
+	assert.NotErrorIs(t, dev.readError(syscall.ENODEV), ErrRevoked)
+	assert.ErrorIs(t, dev.readError(syscall.EIO), syscall.EIO)
+
+	dev.Fn = "/nonexistent/event0"
+	assert.ErrorIs(t, dev.readError(syscall.ENODEV), ErrDeviceGone)
+	assert.NotErrorIs(t, dev.readError(syscall.ENODEV), ErrRevoked)
+
+	dev.revoked = 1
+	assert.ErrorIs(t, dev.readError(syscall.ENODEV), ErrRevoked)
+	assert.NotErrorIs(t, dev.readError(syscall.ENODEV), ErrDeviceGone)
+}
@@ -1,10 +1,89 @@
 package inputeventsubsystem
 
-import "errors"
+import (
+	"errors"
+	"syscall"
+)
 
 var (
 	ErrDriverVersion     = errors.New("unable to get driver version")
 	ErrDeviceInformation = errors.New("unable to get device information")
 	ErrAbsBits           = errors.New("unable to get absbits")
 	ErrEvBits            = errors.New("unable to get evbits")
+	ErrRumbleCanceled    = errors.New("rumble pattern canceled")
+	ErrReplayStopped     = errors.New("replay stopped")
+	ErrRevoked           = errors.New("device access revoked")
+	ErrClockID           = errors.New("unsupported clock id")
+	ErrPermissionDenied  = errors.New("permission denied")
+	ErrNotEvdev          = errors.New("not an evdev device node")
+	ErrDeviceGone        = errors.New("device gone")
+	ErrUnsupported       = errors.New("unsupported ioctl")
+	ErrEventLength       = errors.New("event data is not a whole number of events")
+	ErrEventAlignment    = errors.New("event data is not aligned")
+	ErrAbsInfoLength     = errors.New("absinfo data too short")
+)
+
+// operations reported by DeviceError
+const (
+	OpOpen     = "open"
+	OpRead     = "read"
+	OpWrite    = "write"
+	OpIdentify = "identify" // EVIOCGVERSION and EVIOCGID, fail on anything that is not an evdev node
+	OpProbe    = "probe"
+	OpIoctl    = "ioctl"
 )
+
+// DeviceError carry the failed operation, the device path and the underlying syscall error.
+// errors.Is match the wrapped errno, the sentinel given by Kind and the sentinel family of the errno
+// (ErrPermissionDenied, ErrNotEvdev, ErrDeviceGone, ErrUnsupported)
+type DeviceError struct {
+	Op   string
+	Path string
+	Kind error // optional sentinel kept for the callers testing the historical errors (ErrDriverVersion...)
+	Err  error
+}
+
+func (e *DeviceError) Error() string {
+	msg := e.Op + " " + e.Path
+	if e.Kind != nil {
+		msg += ": " + e.Kind.Error()
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *DeviceError) Unwrap() error {
+	return e.Err
+}
+
+func (e *DeviceError) Is(target error) bool {
+	if e.Kind != nil && target == e.Kind {
+		return true
+	}
+
+	errno, ok := e.Err.(syscall.Errno)
+	if !ok {
+		return false
+	}
+
+	switch target {
+	case ErrPermissionDenied:
+		return errno == syscall.EACCES || errno == syscall.EPERM
+	case ErrDeviceGone:
+		return e.Kind != ErrRevoked && (errno == syscall.ENODEV || errno == syscall.ENOENT || errno == syscall.ENXIO)
+	case ErrNotEvdev:
+		return e.Op == OpIdentify && (errno == syscall.ENOTTY || errno == syscall.EINVAL)
+	case ErrUnsupported:
+		return e.Op != OpIdentify && (errno == syscall.ENOTTY || errno == syscall.EINVAL || errno == syscall.EOPNOTSUPP)
+	}
+	return false
+}
+
+func (dev *Device) wrapError(op string, kind error, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &DeviceError{Op: op, Path: dev.Fn, Kind: kind, Err: err}
+}
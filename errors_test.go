@@ -0,0 +1,38 @@
+package inputeventsubsystem
+
+import (
+	"errors"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeviceError(t *testing.T) {
+	dev := Device{Fn: "/dev/input/event3"}
+
+	err := dev.wrapError(OpOpen, nil, syscall.EACCES)
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+	assert.ErrorIs(t, err, syscall.EACCES)
+	assert.NotErrorIs(t, err, ErrDeviceGone)
+	assert.Equal(t, "open /dev/input/event3: permission denied", err.Error())
+
+	var deverr *DeviceError
+	assert.True(t, errors.As(err, &deverr))
+	assert.Equal(t, OpOpen, deverr.Op)
+	assert.Equal(t, "/dev/input/event3", deverr.Path)
+
+	err = dev.wrapError(OpIdentify, ErrDriverVersion, syscall.ENOTTY)
+	assert.ErrorIs(t, err, ErrNotEvdev)
+	assert.ErrorIs(t, err, ErrDriverVersion)
+	assert.NotErrorIs(t, err, ErrUnsupported)
+
+	err = dev.wrapError(OpIoctl, nil, syscall.ENOTTY)
+	assert.ErrorIs(t, err, ErrUnsupported)
+	assert.NotErrorIs(t, err, ErrNotEvdev)
+
+	err = dev.wrapError(OpRead, nil, syscall.ENODEV)
+	assert.ErrorIs(t, err, ErrDeviceGone)
+
+	assert.Nil(t, dev.wrapError(OpRead, nil, nil))
+}
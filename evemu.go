@@ -0,0 +1,404 @@
+package inputeventsubsystem
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var ErrEvemuSyntax = errors.New("invalid evemu line")
+
+// event types described by the B: lines, in the evemu order
+var evemuTypes = []int{EV_SYN, EV_KEY, EV_REL, EV_ABS, EV_MSC, EV_SW, EV_LED, EV_SND, EV_REP, EV_FF}
+
+// EvemuDevice is the device description of an evemu-record file
+type EvemuDevice struct {
+	Name       string
+	Bus        uint16
+	VendorID   uint16
+	ProductID  uint16
+	Version    uint16
+	Properties []int
+	Codes      map[int][]int // codes of each event type, the keys are the event types of the device
+	Absinfos   map[int]AbsInfo
+}
+
+// EvemuDevice describe the device from its metadata and capabilities
+func (dev *Device) EvemuDevice() (EvemuDevice, error) {
+	if err := dev.ensureCapabilities(); err != nil {
+		return EvemuDevice{}, err
+	}
+
+	d := EvemuDevice{
+		Name:       dev.Name,
+		Bus:        dev.bus,
+		VendorID:   dev.VendorID,
+		ProductID:  dev.ProductID,
+		Version:    dev.Version,
+		Properties: append([]int(nil), dev.Properties...),
+		Codes:      make(map[int][]int),
+		Absinfos:   make(map[int]AbsInfo),
+	}
+
+	for evtype, codes := range dev.Capabilities {
+		d.Codes[evtype] = make([]int, 0, len(codes))
+		for code := range codes {
+			d.Codes[evtype] = append(d.Codes[evtype], code)
+		}
+		sort.Ints(d.Codes[evtype])
+	}
+
+	for code, a := range dev.Absinfos {
+		d.Absinfos[code] = a
+	}
+
+	return d, nil
+}
+
+func evemuCodeCount(evtype int) int {
+	if evtype == EV_REP {
+		return REP_MAX + 1
+	}
+	return eventMaskCodeCount(evtype)
+}
+
+// the bitmasks are written 8 bytes per line, the last line padded with zeros
+func writeEvemuBits(w io.Writer, prefix string, codebits []byte) error {
+	for i := 0; i < len(codebits) || i == 0; i += 8 {
+		line := prefix
+		for j := i; j < i+8; j++ {
+			var b byte
+			if j < len(codebits) {
+				b = codebits[j]
+			}
+			line += fmt.Sprintf(" %02x", b)
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteTo write the N:, I:, P:, B: and A: lines of the description
+func (d *EvemuDevice) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# EVEMU 1.3\n")
+	fmt.Fprintf(&b, "# Input device name: \"%s\"\n", d.Name)
+	fmt.Fprintf(&b, "# Input device ID: bus 0x%x vendor 0x%x product 0x%x version 0x%x\n", d.Bus, d.VendorID, d.ProductID, d.Version)
+	fmt.Fprintf(&b, "N: %s\n", d.Name)
+	fmt.Fprintf(&b, "I: %04x %04x %04x %04x\n", d.Bus, d.VendorID, d.ProductID, d.Version)
+
+	writeEvemuBits(&b, "P:", codesToBits(d.Properties, INPUT_PROP_MAX+1))
+
+	var types []int
+	for evtype := range d.Codes {
+		types = append(types, evtype)
+	}
+
+	for _, evtype := range evemuTypes {
+		codes := types
+		if evtype != EV_SYN {
+			codes = d.Codes[evtype]
+		}
+		writeEvemuBits(&b, fmt.Sprintf("B: %02x", evtype), codesToBits(codes, evemuCodeCount(evtype)))
+	}
+
+	var abscodes []int
+	for code := range d.Absinfos {
+		abscodes = append(abscodes, code)
+	}
+	sort.Ints(abscodes)
+
+	for _, code := range abscodes {
+		a := d.Absinfos[code]
+		fmt.Fprintf(&b, "A: %02x %d %d %d %d %d\n", code, a.Minimum, a.Maximum, a.Fuzz, a.Flat, a.Resolution)
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func codeName(evtype uint16, code uint16) string {
+	var names map[uint16]string
+
+	switch evtype {
+	case EV_SYN:
+		names = SynCodesString
+	case EV_KEY:
+		if name, ok := KeyCodesString[code]; ok {
+			return name
+		}
+		names = BtnCodesString
+	case EV_REL:
+		names = RelCodesString
+	case EV_ABS:
+		names = AbsCodesString
+	case EV_LED:
+		names = LedCodesString
+	case EV_REP:
+		names = RepCodesString
+	case EV_FF:
+		names = FFCodesString
+	}
+
+	if name, ok := names[code]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%x", code)
+}
+
+// EvemuRecorder write events in the evemu-record format, the timestamps are relative to the first event
+type EvemuRecorder struct {
+	w          *bufio.Writer
+	started    bool
+	start      int64
+	lastreport int64
+}
+
+// NewEvemuRecorder write the description of the device and return the recorder of its events
+func NewEvemuRecorder(w io.Writer, desc EvemuDevice) (*EvemuRecorder, error) {
+	r := &EvemuRecorder{w: bufio.NewWriter(w)}
+
+	if _, err := desc.WriteTo(r.w); err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintln(r.w, "################################")
+	fmt.Fprintln(r.w, "#      Waiting for events      #")
+	fmt.Fprintln(r.w, "################################")
+
+	return r, r.w.Flush()
+}
+
+func (r *EvemuRecorder) Record(events ...Event) error {
+
+	for _, ev := range events {
+		ns := ev.Nanoseconds()
+		if !r.started {
+			r.start, r.lastreport, r.started = ns, ns, true
+		}
+
+		elapsed := (ns - r.start) / 1000
+		fmt.Fprintf(r.w, "E: %d.%06d %04x %04x %04d\t", elapsed/1000000, elapsed%1000000, ev.Type, ev.Code, ev.Value)
+
+		if ev.Type == EV_SYN && ev.Code == SYN_REPORT {
+			fmt.Fprintf(r.w, "# ------------ SYN_REPORT (%d) ---------- +%dms\n", ev.Value, (ns-r.lastreport)/1000000)
+			r.lastreport = ns
+		} else {
+			fmt.Fprintf(r.w, "# %s / %-20s %d\n", evtypeString[int(ev.Type)], codeName(ev.Type, ev.Code), ev.Value)
+		}
+	}
+
+	return r.w.Flush()
+}
+
+// EvemuReader parse an evemu-record file, the description is read by NewEvemuReader and the events by Next
+type EvemuReader struct {
+	Device  EvemuDevice
+	scanner *bufio.Scanner
+	line    int
+	pending string
+}
+
+func evemuSyntaxError(line int, text string) error {
+	return fmt.Errorf("%w: line %d: %q", ErrEvemuSyntax, line, text)
+}
+
+func parseEvemuHex(fields []string) ([]uint64, error) {
+	values := make([]uint64, len(fields))
+	for i, field := range fields {
+		v, err := strconv.ParseUint(field, 16, 16)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+func NewEvemuReader(r io.Reader) (*EvemuReader, error) {
+	er := &EvemuReader{scanner: bufio.NewScanner(r)}
+
+	var propbits []byte
+	bits := make(map[int][]byte)
+	absinfos := make(map[int]AbsInfo)
+
+	for er.scanner.Scan() {
+		er.line++
+		text := strings.TrimSpace(er.scanner.Text())
+
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(text, "E:") {
+			er.pending = text
+			break
+		}
+
+		if strings.HasPrefix(text, "N:") {
+			er.Device.Name = strings.TrimSpace(strings.TrimPrefix(text, "N:"))
+			continue
+		}
+
+		fields := strings.Fields(text)
+
+		switch fields[0] {
+		case "I:":
+			values, err := parseEvemuHex(fields[1:])
+			if err != nil || len(values) != 4 {
+				return nil, evemuSyntaxError(er.line, text)
+			}
+			er.Device.Bus, er.Device.VendorID, er.Device.ProductID, er.Device.Version = uint16(values[0]), uint16(values[1]), uint16(values[2]), uint16(values[3])
+
+		case "P:":
+			values, err := parseEvemuHex(fields[1:])
+			if err != nil {
+				return nil, evemuSyntaxError(er.line, text)
+			}
+			for _, v := range values {
+				propbits = append(propbits, byte(v))
+			}
+
+		case "B:":
+			values, err := parseEvemuHex(fields[1:])
+			if err != nil || len(values) < 1 {
+				return nil, evemuSyntaxError(er.line, text)
+			}
+			evtype := int(values[0])
+			for _, v := range values[1:] {
+				bits[evtype] = append(bits[evtype], byte(v))
+			}
+
+		case "A:":
+			// old files have no resolution
+			if len(fields) != 6 && len(fields) != 7 {
+				return nil, evemuSyntaxError(er.line, text)
+			}
+			code, err := strconv.ParseUint(fields[1], 16, 16)
+			if err != nil {
+				return nil, evemuSyntaxError(er.line, text)
+			}
+			var values [5]int32
+			for i, field := range fields[2:] {
+				v, err := strconv.ParseInt(field, 10, 32)
+				if err != nil {
+					return nil, evemuSyntaxError(er.line, text)
+				}
+				values[i] = int32(v)
+			}
+			absinfos[int(code)] = AbsInfo{Minimum: values[0], Maximum: values[1], Fuzz: values[2], Flat: values[3], Resolution: values[4]}
+
+		case "L:", "S:":
+			// led and switch states are not restored
+
+		default:
+			return nil, evemuSyntaxError(er.line, text)
+		}
+	}
+
+	if err := er.scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	er.Device.Properties = bitsToCodes(propbits, len(propbits)*8)
+	er.Device.Codes = make(map[int][]int)
+	er.Device.Absinfos = absinfos
+
+	typebits := bits[EV_SYN]
+	for _, evtype := range bitsToCodes(typebits, len(typebits)*8) {
+		if evtype == EV_SYN {
+			er.Device.Codes[evtype] = []int{}
+			continue
+		}
+		er.Device.Codes[evtype] = bitsToCodes(bits[evtype], len(bits[evtype])*8)
+	}
+
+	return er, nil
+}
+
+func (er *EvemuReader) parseEvent(text string) (Event, error) {
+	var ev Event
+
+	if i := strings.IndexByte(text, '#'); i >= 0 {
+		text = text[:i]
+	}
+
+	fields := strings.Fields(text)
+	if len(fields) != 5 {
+		return ev, evemuSyntaxError(er.line, text)
+	}
+
+	sec, usec, found := strings.Cut(fields[1], ".")
+	if !found {
+		return ev, evemuSyntaxError(er.line, text)
+	}
+	s, err1 := strconv.ParseInt(sec, 10, 64)
+	us, err2 := strconv.ParseInt(usec, 10, 64)
+	values, err3 := parseEvemuHex(fields[2:4])
+	value, err4 := strconv.ParseInt(fields[4], 10, 32)
+	if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
+		return ev, evemuSyntaxError(er.line, text)
+	}
+
+	setTimeval(&ev.Time, s, us)
+	ev.Type = uint16(values[0])
+	ev.Code = uint16(values[1])
+	ev.Value = int32(value)
+	return ev, nil
+}
+
+// Next return the next recorded event, io.EOF at the end of the file
+func (er *EvemuReader) Next() (Event, error) {
+
+	if er.pending != "" {
+		text := er.pending
+		er.pending = ""
+		return er.parseEvent(text)
+	}
+
+	for er.scanner.Scan() {
+		er.line++
+		text := strings.TrimSpace(er.scanner.Text())
+
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		if !strings.HasPrefix(text, "E:") {
+			return Event{}, evemuSyntaxError(er.line, text)
+		}
+		return er.parseEvent(text)
+	}
+
+	if err := er.scanner.Err(); err != nil {
+		return Event{}, err
+	}
+	return Event{}, io.EOF
+}
+
+// ParseEvemu read a whole evemu-record file
+func ParseEvemu(r io.Reader) (EvemuDevice, []Event, error) {
+	er, err := NewEvemuReader(r)
+	if err != nil {
+		return EvemuDevice{}, nil, err
+	}
+
+	var events []Event
+	for {
+		ev, err := er.Next()
+		if err == io.EOF {
+			return er.Device, events, nil
+		}
+		if err != nil {
+			return er.Device, events, err
+		}
+		events = append(events, ev)
+	}
+}
@@ -0,0 +1,119 @@
+package inputeventsubsystem
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var evemuSample = `# EVEMU 1.3
+# Input device name: "Microsoft X-Box 360 pad"
+N: Microsoft X-Box 360 pad
+I: 0003 045e 028e 0114
+P: 00 00 00 00 00 00 00 00
+B: 00 0b 00 00 00 00 00 00 00
+B: 01 00 00 00 00 00 00 00 00
+B: 01 00 00 00 00 00 00 00 00
+B: 01 00 00 00 00 00 00 00 00
+B: 01 00 00 00 00 00 00 00 00
+B: 01 00 00 00 00 00 00 ff 7c
+B: 01 00 00 00 00 00 00 00 00
+B: 01 00 00 00 00 00 00 00 00
+B: 01 00 00 00 00 00 00 00 00
+B: 01 00 00 00 00 00 00 00 00
+B: 01 00 00 00 00 00 00 00 00
+B: 01 00 00 00 00 00 00 00 00
+B: 01 00 00 00 00 00 00 00 00
+B: 02 00 00 00 00 00 00 00 00
+B: 03 3f 00 03 00 00 00 00 00
+A: 00 -32768 32767 16 128 0
+A: 01 -32768 32767 16 128 0
+A: 02 0 255 0 0 0
+A: 03 -32768 32767 16 128
+A: 04 -32768 32767 16 128 0
+A: 05 0 255 0 0 0
+A: 10 -1 1 0 0 0
+A: 11 -1 1 0 0 0
+################################
+#      Waiting for events      #
+################################
+E: 0.000000 0003 0000 -1234	# EV_ABS / ABS_X                -1234
+E: 0.000000 0000 0000 0000	# ------------ SYN_REPORT (0) ---------- +0ms
+E: 0.020034 0001 0130 0001	# EV_KEY / BTN_SOUTH            1
+E: 0.020034 0000 0000 0000	# ------------ SYN_REPORT (0) ---------- +20ms
+`
+
+func TestParseEvemu(t *testing.T) {
+	desc, events, err := ParseEvemu(strings.NewReader(evemuSample))
+	assert.Nil(t, err)
+
+	assert.Equal(t, "Microsoft X-Box 360 pad", desc.Name)
+	assert.Equal(t, uint16(0x03), desc.Bus)
+	assert.Equal(t, uint16(0x045e), desc.VendorID)
+	assert.Equal(t, uint16(0x028e), desc.ProductID)
+	assert.Equal(t, uint16(0x0114), desc.Version)
+	assert.Empty(t, desc.Properties)
+
+	assert.Equal(t, []int{}, desc.Codes[EV_SYN])
+	assert.Equal(t, []int{0x130, 0x131, 0x132, 0x133, 0x134, 0x135, 0x136, 0x137, 0x13a, 0x13b, 0x13c, 0x13d, 0x13e}, desc.Codes[EV_KEY])
+	assert.Equal(t, []int{ABS_X, ABS_Y, ABS_Z, ABS_RX, ABS_RY, ABS_RZ, ABS_HAT0X, ABS_HAT0Y}, desc.Codes[EV_ABS])
+	assert.Equal(t, AbsInfo{Minimum: -32768, Maximum: 32767, Fuzz: 16, Flat: 128}, desc.Absinfos[ABS_RX])
+	assert.Equal(t, AbsInfo{Minimum: -1, Maximum: 1}, desc.Absinfos[ABS_HAT0Y])
+
+	assert.Equal(t, 4, len(events))
+	assert.Equal(t, Event{Type: EV_ABS, Code: ABS_X, Value: -1234}, Event{Type: events[0].Type, Code: events[0].Code, Value: events[0].Value})
+	assert.Equal(t, int64(20034000), events[2].Nanoseconds())
+	assert.Equal(t, uint16(0x130), events[2].Code)
+}
+
+func TestEvemuRoundTrip(t *testing.T) {
+	desc, events, err := ParseEvemu(strings.NewReader(evemuSample))
+	assert.Nil(t, err)
+
+	var buf bytes.Buffer
+	r, err := NewEvemuRecorder(&buf, desc)
+	assert.Nil(t, err)
+	assert.Nil(t, r.Record(events...))
+
+	desc2, events2, err := ParseEvemu(&buf)
+	assert.Nil(t, err)
+	assert.Equal(t, desc, desc2)
+	assert.Equal(t, events, events2)
+}
+
+func TestEvemuRecorderRelativeTime(t *testing.T) {
+	var buf bytes.Buffer
+	r, err := NewEvemuRecorder(&buf, EvemuDevice{Name: "test", Codes: map[int][]int{EV_SYN: {}, EV_REL: {REL_X}}})
+	assert.Nil(t, err)
+
+	var ev Event
+	setTimeval(&ev.Time, 1700000000, 500000)
+	ev.Type, ev.Code, ev.Value = EV_REL, REL_X, -3
+	assert.Nil(t, r.Record(ev))
+
+	setTimeval(&ev.Time, 1700000001, 250000)
+	ev.Type, ev.Code, ev.Value = EV_SYN, SYN_REPORT, 0
+	assert.Nil(t, r.Record(ev))
+
+	assert.Contains(t, buf.String(), "E: 0.000000 0002 0000 -003\t# EV_REL / REL_X")
+	assert.Contains(t, buf.String(), "E: 0.750000 0000 0000 0000\t# ------------ SYN_REPORT (0) ---------- +750ms")
+}
+
+func TestEvemuSyntaxError(t *testing.T) {
+	_, err := NewEvemuReader(strings.NewReader("N: pad\nX: 00\n"))
+	assert.True(t, errors.Is(err, ErrEvemuSyntax))
+
+	er, err := NewEvemuReader(strings.NewReader("N: pad\nE: 0.1 0001\n"))
+	assert.Nil(t, err)
+	_, err = er.Next()
+	assert.True(t, errors.Is(err, ErrEvemuSyntax))
+
+	er, err = NewEvemuReader(strings.NewReader("N: pad\n"))
+	assert.Nil(t, err)
+	_, err = er.Next()
+	assert.Equal(t, io.EOF, err)
+}
@@ -1,10 +1,25 @@
-//go:build !amd64 && !arm64
-// +build !amd64,!arm64
+//go:build 386 || arm || mips || mipsle
+// +build 386 arm mips mipsle
 
 package inputeventsubsystem
 
-import "encoding/binary"
+import (
+	"encoding/binary"
+	"syscall"
+)
+
+// the kernel always fill __sec as an unsigned long on 32-bit, it is decoded unsigned to go past 2038
+var NativeEventLayout = EventLayoutTime64
 
 func GetTimevalValue(data []byte) int32 {
-	return int32(binary.LittleEndian.Uint32(data))
+	return int32(binary.NativeEndian.Uint32(data))
+}
+
+func timevalSeconds(tv syscall.Timeval) int64 {
+	return int64(uint32(tv.Sec))
+}
+
+func setTimeval(tv *syscall.Timeval, sec int64, usec int64) {
+	tv.Sec = int32(sec)
+	tv.Usec = int32(usec)
 }
@@ -1,10 +1,24 @@
-//go:build arm64 || amd64
-// +build arm64 amd64
+//go:build amd64 || arm64 || loong64 || mips64 || mips64le || ppc64 || ppc64le || riscv64 || s390x
+// +build amd64 arm64 loong64 mips64 mips64le ppc64 ppc64le riscv64 s390x
 
 package inputeventsubsystem
 
-import "encoding/binary"
+import (
+	"encoding/binary"
+	"syscall"
+)
+
+var NativeEventLayout = EventLayoutTimeval64
 
 func GetTimevalValue(data []byte) int64 {
-	return int64(binary.LittleEndian.Uint64(data))
+	return int64(binary.NativeEndian.Uint64(data))
+}
+
+func timevalSeconds(tv syscall.Timeval) int64 {
+	return tv.Sec
+}
+
+func setTimeval(tv *syscall.Timeval, sec int64, usec int64) {
+	tv.Sec = sec
+	tv.Usec = usec
 }
@@ -1,26 +1,27 @@
 package inputeventsubsystem
 
 import (
-	"encoding/binary"
 	"fmt"
 	"sync"
 	"syscall"
+	"time"
 	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	CLOCK_REALTIME  = unix.CLOCK_REALTIME
+	CLOCK_MONOTONIC = unix.CLOCK_MONOTONIC
+	CLOCK_BOOTTIME  = unix.CLOCK_BOOTTIME
 )
 
 const deviceinputeventsize int = int(unsafe.Sizeof(Event{}))
-const sizetimeval = int(unsafe.Sizeof(syscall.Timeval{}))
 
 var eventPool = sync.Pool{
 	New: func() interface{} { return new(Event) },
 }
 
-var eventPoolUnsafe = sync.Pool{
-	New: func() interface{} {
-		return new([deviceinputeventsize * 64]byte)
-	},
-}
-
 type Event struct {
 	Time  syscall.Timeval // time in seconds since epoch at which event occurred
 	Type  uint16          // event code related to the event type
@@ -60,6 +61,14 @@ func (ev Event) String() string {
 		return fmt.Sprintf("{ time %d.%d, type %d (%s), code %d (%s), value %02d }",
 			ev.Time.Sec, ev.Time.Usec, ev.Type, evtypeString[int(ev.Type)], ev.Code, AbsCodesString[ev.Code], ev.Value)
 
+	case EV_LED:
+		return fmt.Sprintf("{ time %d.%d, type %d (%s), code %d (%s), value %02d }",
+			ev.Time.Sec, ev.Time.Usec, ev.Type, evtypeString[int(ev.Type)], ev.Code, LedCodesString[ev.Code], ev.Value)
+
+	case EV_REP:
+		return fmt.Sprintf("{ time %d.%d, type %d (%s), code %d (%s), value %02d }",
+			ev.Time.Sec, ev.Time.Usec, ev.Type, evtypeString[int(ev.Type)], ev.Code, RepCodesString[ev.Code], ev.Value)
+
 	default:
 		return fmt.Sprintf("{ time %d.%d, code %02d, type %s, value %02d }",
 			ev.Time.Sec, ev.Time.Usec, ev.Code, evtypeString[int(ev.Type)], ev.Value)
@@ -67,32 +76,74 @@ func (ev Event) String() string {
 
 }
 
-func UnpackDeviceInputEvents(data []byte) []*Event {
-	var events []*Event = make([]*Event, 0)
-	var bytesconsum int = 0
-	var i int = 0
+func clockNow(clockid int) time.Duration {
+	var ts unix.Timespec
+	if err := unix.ClockGettime(int32(clockid), &ts); err != nil {
+		return 0
+	}
+	return time.Duration(ts.Nano())
+}
 
-	for {
-		ev := eventPool.Get().(*Event)
+// Nanoseconds return the event time in nanoseconds since the origin of the event clock
+func (ev Event) Nanoseconds() int64 {
+	return timevalSeconds(ev.Time)*int64(time.Second) + int64(ev.Time.Usec)*int64(time.Microsecond)
+}
 
-		ev.Time.Sec = GetTimevalValue(data[i*deviceinputeventsize:])
-		ev.Time.Usec = GetTimevalValue(data[i*deviceinputeventsize+sizetimeval/2:])
+// AsTime return the event time as is, seconds since the origin of the event clock
+func (ev Event) AsTime() time.Time {
+	return time.Unix(timevalSeconds(ev.Time), int64(ev.Time.Usec)*int64(time.Microsecond))
+}
 
-		ev.Type = binary.LittleEndian.Uint16(data[i*deviceinputeventsize+sizetimeval : i*deviceinputeventsize+sizetimeval+2])
-		ev.Code = binary.LittleEndian.Uint16(data[i*deviceinputeventsize+sizetimeval+2 : i*deviceinputeventsize+sizetimeval+4])
-		ev.Value = int32(binary.LittleEndian.Uint32(data[i*deviceinputeventsize+sizetimeval+4 : i*deviceinputeventsize+sizetimeval+8]))
+// Timestamp return the wall time of the event. With CLOCK_MONOTONIC and CLOCK_BOOTTIME the event time is
+// mapped on the wall clock with the current offset between both clocks
+func (ev Event) Timestamp(clockid int) time.Time {
+	if clockid == CLOCK_REALTIME {
+		return ev.AsTime()
+	}
+	return time.Now().Add(-ev.Latency(clockid))
+}
+
+// Latency return the time elapsed since the event occurred, measured on the clock that timestamped it
+func (ev Event) Latency(clockid int) time.Duration {
+	return clockNow(clockid) - time.Duration(ev.Nanoseconds())
+}
+
+// UnpackDeviceInputEvents decode data in pooled events, data must hold a whole number of events
+func UnpackDeviceInputEvents(data []byte) ([]*Event, error) {
+	if len(data)%deviceinputeventsize != 0 {
+		return nil, fmt.Errorf("%w: %d bytes", ErrEventLength, len(data))
+	}
+
+	var events []*Event = make([]*Event, 0, len(data)/deviceinputeventsize)
+
+	for i := 0; i < len(data); i += deviceinputeventsize {
+		ev := eventPool.Get().(*Event)
+
+		var sec, usec int64
+		sec, usec, ev.Type, ev.Code, ev.Value = NativeEventLayout.Decode(data[i : i+deviceinputeventsize])
+		setTimeval(&ev.Time, sec, usec)
 
 		events = append(events, ev)
-		bytesconsum = bytesconsum + deviceinputeventsize
-		if bytesconsum+deviceinputeventsize > len(data) {
-			break
-		}
-		i++
 	}
-	return events
+	return events, nil
+}
+
+// UnsafeUnpackDeviceInputEvents alias data as events, the capacity of the result cover the capacity of data.
+// data must hold a whole number of events and be aligned like an Event
+func UnsafeUnpackDeviceInputEvents(data []byte) ([]Event, error) {
+	if len(data)%deviceinputeventsize != 0 {
+		return nil, fmt.Errorf("%w: %d bytes", ErrEventLength, len(data))
+	}
+
+	if uintptr(unsafe.Pointer(unsafe.SliceData(data)))%unsafe.Alignof(Event{}) != 0 {
+		return nil, ErrEventAlignment
+	}
+
+	return eventsOf(data), nil
 }
 
-func UnsafeUnpackDeviceInputEvents(data []byte) []Event {
-	ev := (*[]Event)(unsafe.Pointer(&data))
-	return (*ev)[:len(data)/deviceinputeventsize]
+// eventsOf alias a buffer known to be valid, like the pooled read buffers
+func eventsOf(data []byte) []Event {
+	ev := unsafe.Slice((*Event)(unsafe.Pointer(unsafe.SliceData(data))), cap(data)/deviceinputeventsize)
+	return ev[:len(data)/deviceinputeventsize]
 }
@@ -1,9 +1,14 @@
 package inputeventsubsystem
 
 import (
+	"os"
+	"syscall"
 	"testing"
+	"time"
+	"unsafe"
 
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
 )
 
 var data []byte = []byte{
@@ -33,9 +38,18 @@ var data2 []byte = []byte{
 	0xEE, 0xFF, 0x78, 0x78,
 }
 
+// alignedCopy copy raw in an Event backed buffer, the static samples are not aligned for UnsafeUnpackDeviceInputEvents
+func alignedCopy(raw []byte, shift int) []byte {
+	backing := make([]Event, len(raw)/deviceinputeventsize+2)
+	buf := unsafe.Slice((*byte)(unsafe.Pointer(&backing[0])), len(backing)*deviceinputeventsize)
+	buf = buf[shift : shift+len(raw)]
+	copy(buf, raw)
+	return buf
+}
+
 func BenchmarkUnpackDeviceInputEvents(b *testing.B) {
 	for it := 0; it < b.N; it++ {
-		e := UnpackDeviceInputEvents(data)
+		e, _ := UnpackDeviceInputEvents(data)
 		for _, ev := range e {
 			eventPool.Put(ev)
 		}
@@ -43,14 +57,17 @@ func BenchmarkUnpackDeviceInputEvents(b *testing.B) {
 }
 
 func BenchmarkUnsafeUnpackDeviceInputEvents(b *testing.B) {
+	data := alignedCopy(data, 0)
 	for it := 0; it < b.N; it++ {
 		UnsafeUnpackDeviceInputEvents(data)
 	}
 }
 func TestUnit(t *testing.T) {
-	e := UnpackDeviceInputEvents(data)
+	e, err := UnpackDeviceInputEvents(data)
+	assert.Nil(t, err)
 
-	e2 := UnsafeUnpackDeviceInputEvents(data)
+	e2, err := UnsafeUnpackDeviceInputEvents(alignedCopy(data, 0))
+	assert.Nil(t, err)
 
 	for index, ev := range e {
 		assert.Equal(t, (e2)[index].Value, ev.Value)
@@ -61,9 +78,11 @@ func TestUnit(t *testing.T) {
 
 	}
 
-	e = UnpackDeviceInputEvents(data2)
+	e, err = UnpackDeviceInputEvents(data2)
+	assert.Nil(t, err)
 
-	e2 = UnsafeUnpackDeviceInputEvents(data2)
+	e2, err = UnsafeUnpackDeviceInputEvents(alignedCopy(data2, 0))
+	assert.Nil(t, err)
 
 	for index, ev := range e {
 		assert.Equal(t, (e2)[index].Value, ev.Value)
@@ -75,3 +94,137 @@ func TestUnit(t *testing.T) {
 	}
 
 }
+
+func FuzzUnpackDeviceInputEvents(f *testing.F) {
+	f.Add(data)
+	f.Add(data2)
+	f.Add(data[:deviceinputeventsize-1])
+	f.Add(data[:deviceinputeventsize+1])
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, raw []byte) {
+		events, err := UnpackDeviceInputEvents(raw)
+		if len(raw)%deviceinputeventsize != 0 {
+			assert.ErrorIs(t, err, ErrEventLength)
+			return
+		}
+
+		assert.Nil(t, err)
+		assert.Len(t, events, len(raw)/deviceinputeventsize)
+		for i, ev := range events {
+			sec, usec, evtype, code, value := NativeEventLayout.Decode(raw[i*deviceinputeventsize:])
+			assert.Equal(t, sec, timevalSeconds(ev.Time))
+			assert.Equal(t, usec, int64(ev.Time.Usec))
+			assert.Equal(t, evtype, ev.Type)
+			assert.Equal(t, code, ev.Code)
+			assert.Equal(t, value, ev.Value)
+			eventPool.Put(ev)
+		}
+	})
+}
+
+func FuzzUnsafeUnpackDeviceInputEvents(f *testing.F) {
+	f.Add(data, uint8(0))
+	f.Add(data2, uint8(0))
+	f.Add(data, uint8(1))
+	f.Add(data[:deviceinputeventsize-1], uint8(0))
+
+	f.Fuzz(func(t *testing.T, raw []byte, offset uint8) {
+		shift := int(offset) % deviceinputeventsize
+
+		events, err := UnsafeUnpackDeviceInputEvents(alignedCopy(raw, shift))
+		switch {
+		case len(raw)%deviceinputeventsize != 0:
+			assert.ErrorIs(t, err, ErrEventLength)
+		case uintptr(shift)%unsafe.Alignof(Event{}) != 0:
+			assert.ErrorIs(t, err, ErrEventAlignment)
+		default:
+			assert.Nil(t, err)
+			assert.Len(t, events, len(raw)/deviceinputeventsize)
+			for i, ev := range events {
+				_, _, evtype, code, value := NativeEventLayout.Decode(raw[i*deviceinputeventsize:])
+				assert.Equal(t, evtype, ev.Type)
+				assert.Equal(t, code, ev.Code)
+				assert.Equal(t, value, ev.Value)
+			}
+		}
+	})
+}
+
+func TestEventTimestamp(t *testing.T) {
+	for _, clockid := range []int{CLOCK_REALTIME, CLOCK_MONOTONIC, CLOCK_BOOTTIME} {
+		ev := Event{Time: syscall.NsecToTimeval(int64(clockNow(clockid) - time.Second))}
+
+		assert.InDelta(t, float64(time.Second), float64(ev.Latency(clockid)), float64(100*time.Millisecond))
+		assert.WithinDuration(t, time.Now().Add(-time.Second), ev.Timestamp(clockid), 100*time.Millisecond)
+	}
+}
+
+func TestDeviceEventTimestamp(t *testing.T) {
+	dev, err := OpenBackend("fake", fakeGamepad())
+	assert.Nil(t, err)
+	defer dev.Close()
+
+	// a monotonic time read with the realtime clock would be decades old
+	assert.Nil(t, dev.SetClock(CLOCK_MONOTONIC))
+	ev := Event{Time: syscall.NsecToTimeval(int64(clockNow(CLOCK_MONOTONIC) - time.Second))}
+
+	assert.InDelta(t, float64(time.Second), float64(dev.EventLatency(ev)), float64(100*time.Millisecond))
+	assert.WithinDuration(t, time.Now().Add(-time.Second), dev.EventTimestamp(ev), 100*time.Millisecond)
+}
+
+// pipeDevice return a device reading the read end of a pipe, the events are written on the returned file descriptor
+func pipeDevice(tb testing.TB) (*Device, int) {
+	var p [2]int
+	if err := unix.Pipe2(p[:], unix.O_NONBLOCK|unix.O_CLOEXEC); err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() {
+		unix.Close(p[1])
+	})
+	return &Device{Fn: "pipe", backend: fdBackend{fd: p[0]}}, p[1]
+}
+
+func BenchmarkReadInto(b *testing.B) {
+	dev, w := pipeDevice(b)
+	defer dev.Close()
+
+	var buf [64]Event
+	b.ReportAllocs()
+	for it := 0; it < b.N; it++ {
+		unix.Write(w, data)
+		dev.ReadInto(buf[:])
+	}
+}
+
+func TestReadInto(t *testing.T) {
+	dev, w := pipeDevice(t)
+
+	var buf [64]Event
+
+	unix.Write(w, data)
+	n, err := dev.ReadInto(buf[:])
+	assert.Nil(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, eventsOf(alignedCopy(data, 0)), buf[:n])
+
+	allocs := testing.AllocsPerRun(100, func() {
+		unix.Write(w, data)
+		dev.ReadInto(buf[:])
+	})
+	assert.Equal(t, float64(0), allocs)
+
+	unix.Write(w, data)
+	unix.Write(w, data)
+	it := dev.Iterator(buf[:2])
+	var codes []uint16
+	for i := 0; i < 6 && it.Next(); i++ {
+		codes = append(codes, it.Event().Code)
+	}
+	assert.Nil(t, it.Err())
+	assert.Equal(t, []uint16{4, KEY_C, SYN_REPORT, 4, KEY_C, SYN_REPORT}, codes)
+
+	dev.Close()
+	assert.False(t, it.Next())
+	assert.Equal(t, os.ErrClosed, it.Err())
+}
@@ -0,0 +1,182 @@
+package inputeventsubsystem
+
+import (
+	"errors"
+	"syscall"
+)
+
+var ErrEventMaskType = errors.New("event type can not be masked")
+
+// number of codes of each event type maskable by EVIOCSMASK, EV_SYN mask the event types
+func eventMaskCodeCount(evtype int) int {
+	switch evtype {
+	case EV_SYN:
+		return EV_MAX + 1
+	case EV_KEY:
+		return KEY_MAX + 1
+	case EV_REL:
+		return REL_MAX + 1
+	case EV_ABS:
+		return ABS_MAX + 1
+	case EV_MSC:
+		return 0x07 + 1
+	case EV_SW:
+		return 0x10 + 1
+	case EV_LED:
+		return LED_MAX + 1
+	case EV_SND:
+		return 0x07 + 1
+	case EV_FF:
+		return FF_MAX + 1
+	}
+	return 0
+}
+
+func codesToBits(codes []int, count int) []byte {
+	var codebits []byte = make([]byte, (count+7)/8)
+
+	for _, code := range codes {
+		if code >= 0 && code < count {
+			codebits[code/8] |= 1 << uint(code%8)
+		}
+	}
+	return codebits
+}
+
+func bitsToCodes(codebits []byte, count int) []int {
+	var codes []int = make([]int, 0)
+
+	for code := 0; code < count; code++ {
+		if codebits[code/8]&(1<<uint(code%8)) != 0 {
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
+
+// SetEventMask ask the kernel to deliver only the listed codes of evtype (event types if evtype is EV_SYN).
+// On kernels without EVIOCSMASK the events are filtered in userspace by the read loop
+func (dev *Device) SetEventMask(evtype int, codes []int) error {
+
+	count := eventMaskCodeCount(evtype)
+	if count == 0 {
+		return ErrEventMaskType
+	}
+
+	codebits := codesToBits(codes, count)
+
+	err := dev.backend.SetEventMask(evtype, codebits)
+	if err == syscall.ENOTTY || err == syscall.EINVAL {
+		dev.setSoftEventMask(evtype, codebits)
+		err = nil
+	}
+
+	if err == nil {
+		dev.eventmasks[evtype] = append([]int(nil), codes...)
+	}
+
+	return dev.wrapError(OpIoctl, nil, err)
+}
+
+// GetEventMask return the codes of evtype delivered by the kernel (or by the userspace fallback)
+func (dev *Device) GetEventMask(evtype int) ([]int, error) {
+
+	count := eventMaskCodeCount(evtype)
+	if count == 0 {
+		return nil, ErrEventMaskType
+	}
+
+	if softmask := dev.softEventMask(); softmask != nil {
+		if codebits, ok := softmask[evtype]; ok {
+			return bitsToCodes(codebits, count), nil
+		}
+	}
+
+	codebits := make([]byte, (count+7)/8)
+
+	err := dev.backend.EventMask(evtype, codebits)
+	if err == syscall.ENOTTY || err == syscall.EINVAL {
+		for i := range codebits {
+			codebits[i] = 0xff
+		}
+		err = nil
+	}
+
+	if err != nil {
+		return nil, dev.wrapError(OpIoctl, nil, err)
+	}
+
+	return bitsToCodes(codebits, count), nil
+}
+
+func (dev *Device) softEventMask() map[int][]byte {
+	if softmask, ok := dev.softmask.Load().(map[int][]byte); ok {
+		return softmask
+	}
+	return nil
+}
+
+// the mask is copied on write so the read loop never lock
+func (dev *Device) setSoftEventMask(evtype int, codebits []byte) {
+	softmask := make(map[int][]byte)
+
+	for t, bits := range dev.softEventMask() {
+		softmask[t] = bits
+	}
+	softmask[evtype] = codebits
+
+	dev.softmask.Store(softmask)
+}
+
+// eventMasked follow __evdev_is_filtered: EV_SYN and the unknown types are never masked, nor the codes past the
+// count of their type
+func eventMasked(softmask map[int][]byte, evtype uint16, code uint16) bool {
+
+	if evtype == EV_SYN || evtype > EV_MAX {
+		return false
+	}
+
+	if typebits, ok := softmask[EV_SYN]; ok && typebits[evtype/8]&(1<<(evtype%8)) == 0 {
+		return true
+	}
+
+	if int(code) >= eventMaskCodeCount(int(evtype)) {
+		return false
+	}
+
+	codebits, ok := softmask[int(evtype)]
+	return ok && codebits[code/8]&(1<<(code%8)) == 0
+}
+
+func (dev *Device) filterEvents(events []*Event) []*Event {
+	softmask := dev.softEventMask()
+	if softmask == nil {
+		return events
+	}
+
+	filtered := events[:0]
+	for _, ev := range events {
+		if eventMasked(softmask, ev.Type, ev.Code) {
+			eventPool.Put(ev)
+		} else {
+			filtered = append(filtered, ev)
+		}
+	}
+	return filtered
+}
+
+// the events are compacted in place so the slice keep starting at the pooled buffer
+func (dev *Device) filterUnsafeEvents(events []Event) []Event {
+	softmask := dev.softEventMask()
+	if softmask == nil {
+		return events
+	}
+
+	filtered := events[:0]
+	for _, ev := range events {
+		if !eventMasked(softmask, ev.Type, ev.Code) {
+			filtered = append(filtered, ev)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,39 @@
+package inputeventsubsystem
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSoftEventMask(t *testing.T) {
+	var dev Device
+
+	// the unsafe filter compact in place, work on a copy of the shared sample
+	sample := append([]byte(nil), data...)
+
+	events, err := UnsafeUnpackDeviceInputEvents(sample)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(dev.filterUnsafeEvents(events)))
+
+	dev.setSoftEventMask(EV_KEY, codesToBits([]int{KEY_A}, eventMaskCodeCount(EV_KEY)))
+
+	// KEY_C is dropped, EV_MSC and EV_SYN are not masked
+	events, _ = UnsafeUnpackDeviceInputEvents(sample)
+	events = dev.filterUnsafeEvents(events)
+	assert.Len(t, events, 2)
+	assert.Equal(t, uint16(EV_MSC), events[0].Type)
+	assert.Equal(t, uint16(EV_SYN), events[1].Type)
+
+	dev.setSoftEventMask(EV_SYN, codesToBits([]int{EV_KEY, EV_ABS}, eventMaskCodeCount(EV_SYN)))
+
+	// like the kernel, the SYN_REPORT is kept whatever the type mask
+	decoded, err := UnpackDeviceInputEvents(data)
+	assert.Nil(t, err)
+	filtered := dev.filterEvents(decoded)
+	assert.Len(t, filtered, 1)
+	assert.Equal(t, uint16(EV_SYN), filtered[0].Type)
+	assert.Equal(t, uint16(SYN_REPORT), filtered[0].Code)
+
+	assert.Equal(t, []int{EV_KEY, EV_ABS}, bitsToCodes(dev.softEventMask()[EV_SYN], eventMaskCodeCount(EV_SYN)))
+}
@@ -0,0 +1,419 @@
+package inputeventsubsystem
+
+import (
+	"encoding/binary"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// FakeDevice is an in-memory Backend scripted by the tests: capabilities and absinfos from a description,
+// key and led states, and the events injected for the readers. Open it with OpenBackend
+type FakeDevice struct {
+	lock     sync.Mutex
+	desc     EvemuDevice
+	phys     string
+	uniq     string
+	keys     map[int]bool
+	leds     map[int]bool
+	pending  []byte
+	ready    chan struct{} // closed and replaced when events are injected or the device change
+	written  []Event
+	grabbed  bool
+	delay    uint32
+	period   uint32
+	effects  map[int16][]byte
+	nextID   int16
+	closed   bool
+	gone     bool
+	revoked  bool
+	clockid  int
+	syncs    int
+	keycodes map[uint32]uint32
+}
+
+func NewFakeDevice(desc EvemuDevice) *FakeDevice {
+	f := &FakeDevice{
+		desc:     desc,
+		keys:     make(map[int]bool),
+		leds:     make(map[int]bool),
+		ready:    make(chan struct{}),
+		delay:    250,
+		period:   33,
+		effects:  make(map[int16][]byte),
+		keycodes: make(map[uint32]uint32),
+	}
+
+	if f.desc.Codes == nil {
+		f.desc.Codes = make(map[int][]int)
+	}
+	if f.desc.Absinfos == nil {
+		f.desc.Absinfos = make(map[int]AbsInfo)
+	}
+	return f
+}
+
+func (f *FakeDevice) notifyLocked() {
+	close(f.ready)
+	f.ready = make(chan struct{})
+}
+
+// SetPhysUniq set the phys and uniq strings reported by the device
+func (f *FakeDevice) SetPhysUniq(phys string, uniq string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.phys, f.uniq = phys, uniq
+}
+
+// SetKey change the key state without event, like a key held before the device was opened
+func (f *FakeDevice) SetKey(code int, pressed bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.keys[code] = pressed
+}
+
+// Inject queue events for the readers, the key, led and axis states follow them like in the kernel
+func (f *FakeDevice) Inject(events ...Event) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	for _, ev := range events {
+		switch ev.Type {
+		case EV_KEY:
+			if ev.Value != 2 {
+				f.keys[int(ev.Code)] = ev.Value != 0
+			}
+		case EV_LED:
+			f.leds[int(ev.Code)] = ev.Value != 0
+		case EV_ABS:
+			if a, ok := f.desc.Absinfos[int(ev.Code)]; ok {
+				a.Value = ev.Value
+				f.desc.Absinfos[int(ev.Code)] = a
+			}
+		}
+		f.pending = append(f.pending, (*[deviceinputeventsize]byte)(unsafe.Pointer(&ev))[:]...)
+	}
+	f.notifyLocked()
+}
+
+// Disconnect make the reads fail like an unplugged device
+func (f *FakeDevice) Disconnect() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.gone = true
+	f.notifyLocked()
+}
+
+// Written return the events written to the device (leds, force feedback...)
+func (f *FakeDevice) Written() []Event {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]Event(nil), f.written...)
+}
+
+func (f *FakeDevice) Grabbed() bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.grabbed
+}
+
+func (f *FakeDevice) Read(p []byte) (int, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	switch {
+	case f.closed:
+		return 0, syscall.EBADF
+	case f.gone || f.revoked:
+		return 0, syscall.ENODEV
+	case len(p) < deviceinputeventsize:
+		return 0, syscall.EINVAL
+	case len(f.pending) == 0:
+		return 0, syscall.EWOULDBLOCK
+	}
+
+	// like evdev, only whole events are read
+	n := copy(p[:len(p)/deviceinputeventsize*deviceinputeventsize], f.pending)
+	f.pending = f.pending[n:]
+	return n, nil
+}
+
+func (f *FakeDevice) Wait(timeout time.Duration) error {
+	f.lock.Lock()
+	if f.closed {
+		f.lock.Unlock()
+		return syscall.EBADF
+	}
+	if len(f.pending) > 0 || f.gone || f.revoked {
+		f.lock.Unlock()
+		return nil
+	}
+	ready := f.ready
+	f.lock.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-ready:
+	case <-timer.C:
+	}
+	return nil
+}
+
+func (f *FakeDevice) Write(p []byte) (int, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.closed {
+		return 0, syscall.EBADF
+	}
+	if f.gone || f.revoked {
+		return 0, syscall.ENODEV
+	}
+
+	events, err := UnpackDeviceInputEvents(p)
+	if err != nil {
+		return 0, syscall.EINVAL
+	}
+
+	for _, ev := range events {
+		if ev.Type == EV_LED {
+			f.leds[int(ev.Code)] = ev.Value != 0
+		}
+		f.written = append(f.written, *ev)
+		eventPool.Put(ev)
+	}
+	return len(p), nil
+}
+
+func (f *FakeDevice) Sync() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.syncs++
+	return nil
+}
+
+func (f *FakeDevice) Close() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.closed {
+		return syscall.EBADF
+	}
+	f.closed = true
+	f.notifyLocked()
+	return nil
+}
+
+func (f *FakeDevice) Version() (uint32, error) {
+	return 0x010001, nil
+}
+
+func (f *FakeDevice) ID() (uint16, uint16, uint16, uint16, error) {
+	return f.desc.Bus, f.desc.VendorID, f.desc.ProductID, f.desc.Version, nil
+}
+
+func (f *FakeDevice) Name() (string, error) {
+	return f.desc.Name, nil
+}
+
+func (f *FakeDevice) Phys() (string, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.phys, nil
+}
+
+func (f *FakeDevice) Uniq() (string, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.uniq, nil
+}
+
+func (f *FakeDevice) Props() ([]byte, error) {
+	return codesToBits(f.desc.Properties, INPUT_PROP_MAX+1), nil
+}
+
+func (f *FakeDevice) Bits(evtype int, max int) ([]byte, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	var codes []int
+	if evtype == 0 {
+		for t := range f.desc.Codes {
+			codes = append(codes, t)
+		}
+	} else {
+		codes = f.desc.Codes[evtype]
+	}
+
+	return codesToBits(codes, max+1), nil
+}
+
+func (f *FakeDevice) AbsInfo(code int) (AbsInfo, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	a, ok := f.desc.Absinfos[code]
+	if !ok {
+		return a, syscall.EINVAL
+	}
+	return a, nil
+}
+
+func (f *FakeDevice) SetAbsInfo(code int, a AbsInfo) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if _, ok := f.desc.Absinfos[code]; !ok {
+		return syscall.EINVAL
+	}
+	f.desc.Absinfos[code] = a
+	return nil
+}
+
+func stateBits(state map[int]bool, count int) []byte {
+	var codes []int
+	for code, on := range state {
+		if on {
+			codes = append(codes, code)
+		}
+	}
+	return codesToBits(codes, count)
+}
+
+func (f *FakeDevice) KeyState() ([]byte, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return stateBits(f.keys, KEY_MAX+1), nil
+}
+
+func (f *FakeDevice) LedState() ([]byte, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return stateBits(f.leds, LED_MAX+1), nil
+}
+
+func (f *FakeDevice) Grab(acquire bool) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if acquire && f.grabbed {
+		return syscall.EBUSY
+	}
+	f.grabbed = acquire
+	return nil
+}
+
+func (f *FakeDevice) Revoke() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.revoked = true
+	f.notifyLocked()
+	return nil
+}
+
+func (f *FakeDevice) SetClockID(clockid int) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.clockid = clockid
+	return nil
+}
+
+func (f *FakeDevice) Repeat() (uint32, uint32, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.delay, f.period, nil
+}
+
+func (f *FakeDevice) SetRepeat(delay uint32, period uint32) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.delay, f.period = delay, period
+	return nil
+}
+
+func (f *FakeDevice) ScanCode(key uint16) (uint16, error) {
+	return 0, syscall.ENOTTY
+}
+
+// Keycode implement the keymap by scancode, the keymap start empty
+func (f *FakeDevice) Keycode(entry []byte) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	var k KeymapEntry
+	k.Unpack(entry)
+
+	scancode, err := k.ScancodeUint32()
+	if err != nil || entry[0]&INPUT_KEYMAP_BY_INDEX != 0 {
+		return syscall.EINVAL
+	}
+
+	keycode, ok := f.keycodes[scancode]
+	if !ok {
+		return syscall.EINVAL
+	}
+	binary.NativeEndian.PutUint32(entry[4:], keycode)
+	return nil
+}
+
+func (f *FakeDevice) SetKeycode(entry []byte) error {
+	var k KeymapEntry
+	k.Unpack(entry)
+
+	scancode, err := k.ScancodeUint32()
+	if err != nil || entry[0]&INPUT_KEYMAP_BY_INDEX != 0 {
+		return syscall.EINVAL
+	}
+	return f.SetLegacyKeycode(scancode, k.Keycode)
+}
+
+func (f *FakeDevice) SetLegacyKeycode(scancode uint32, keycode uint32) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.keycodes[scancode] = keycode
+	return nil
+}
+
+// UploadEffect allocate the ids of the new effects (id -1) like the kernel
+func (f *FakeDevice) UploadEffect(effect []byte) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	id := int16(binary.NativeEndian.Uint16(effect[2:]))
+	if id == ffEffectNewID {
+		id = f.nextID
+		f.nextID++
+		binary.NativeEndian.PutUint16(effect[2:], uint16(id))
+	} else if _, ok := f.effects[id]; !ok {
+		return syscall.EINVAL
+	}
+	f.effects[id] = append([]byte(nil), effect...)
+	return nil
+}
+
+func (f *FakeDevice) EraseEffect(id int16) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if _, ok := f.effects[id]; !ok {
+		return syscall.EINVAL
+	}
+	delete(f.effects, id)
+	return nil
+}
+
+// the event masks are left to the userspace fallback of Device
+func (f *FakeDevice) EventMask(evtype int, codebits []byte) error {
+	return syscall.ENOTTY
+}
+
+func (f *FakeDevice) SetEventMask(evtype int, codebits []byte) error {
+	return syscall.ENOTTY
+}
+
+func (f *FakeDevice) Ioctl(request uintptr, arg unsafe.Pointer) error {
+	return syscall.ENOTTY
+}
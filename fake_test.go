@@ -0,0 +1,127 @@
+package inputeventsubsystem
+
+import (
+	"errors"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func fakeGamepad() *FakeDevice {
+	return NewFakeDevice(EvemuDevice{
+		Name:      "fake pad",
+		Bus:       0x03,
+		VendorID:  0x045e,
+		ProductID: 0x028e,
+		Codes: map[int][]int{
+			EV_SYN: {SYN_REPORT},
+			EV_KEY: {BTN_SOUTH, BTN_EAST},
+			EV_ABS: {ABS_X, ABS_Y},
+			EV_LED: {LED_NUML},
+			EV_FF:  {FF_RUMBLE},
+		},
+		Absinfos: map[int]AbsInfo{
+			ABS_X: {Minimum: -32768, Maximum: 32767, Flat: 128},
+			ABS_Y: {Minimum: -32768, Maximum: 32767, Flat: 128},
+		},
+	})
+}
+
+func TestFakeDeviceOpen(t *testing.T) {
+	fake := fakeGamepad()
+	fake.SetKey(BTN_EAST, true)
+
+	dev, err := OpenBackend("fake", fake)
+	assert.Nil(t, err)
+	defer dev.Close()
+
+	assert.Equal(t, "fake pad", dev.Name)
+	assert.Equal(t, uint16(0x045e), dev.VendorID)
+	assert.Contains(t, dev.Capabilities[EV_KEY], BTN_SOUTH)
+	assert.Equal(t, int32(32767), dev.Absinfos[ABS_X].Maximum)
+
+	keybits, err := dev.KeysState()
+	assert.Nil(t, err)
+	assert.Equal(t, []int{BTN_EAST}, bitsToCodes(keybits, KEY_MAX+1))
+
+	assert.Nil(t, dev.Grab(true))
+	assert.True(t, fake.Grabbed())
+
+	axis, err := dev.AxisDevice(false, 0)
+	assert.Nil(t, err)
+	min, max := axis.GetRange(ABS_Y)
+	assert.Equal(t, int32(-32768), min)
+	assert.Equal(t, int32(32767), max)
+}
+
+func TestFakeDeviceRead(t *testing.T) {
+	fake := fakeGamepad()
+
+	dev, err := OpenBackend("fake", fake)
+	assert.Nil(t, err)
+	defer dev.Close()
+
+	events := dev.Read()
+	fake.Inject(Event{Type: EV_ABS, Code: ABS_X, Value: 1000}, Event{Type: EV_SYN, Code: SYN_REPORT})
+
+	select {
+	case evs := <-events:
+		assert.Equal(t, 2, len(evs))
+		assert.Equal(t, int32(1000), evs[0].Value)
+		dev.ReadDone(evs)
+	case <-time.After(time.Second):
+		t.Fatal("no events")
+	}
+
+	a, err := fake.AbsInfo(ABS_X)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1000), a.Value)
+
+	fake.Disconnect()
+	select {
+	case err := <-dev.Error():
+		assert.True(t, errors.Is(err, ErrDeviceGone))
+	case <-time.After(time.Second):
+		t.Fatal("no error")
+	}
+}
+
+func TestFakeDeviceShortRead(t *testing.T) {
+	fake := fakeGamepad()
+	fake.Inject(Event{Type: EV_ABS, Code: ABS_X, Value: 1000}, Event{Type: EV_SYN, Code: SYN_REPORT})
+
+	// like evdev, a buffer too small for one event is refused
+	n, err := fake.Read(make([]byte, deviceinputeventsize-1))
+	assert.Equal(t, 0, n)
+	assert.Equal(t, syscall.EINVAL, err)
+
+	// only the whole events fitting the buffer are read
+	n, err = fake.Read(make([]byte, deviceinputeventsize+1))
+	assert.Nil(t, err)
+	assert.Equal(t, deviceinputeventsize, n)
+}
+
+func TestFakeDeviceWrite(t *testing.T) {
+	fake := fakeGamepad()
+
+	dev, err := OpenBackend("fake", fake)
+	assert.Nil(t, err)
+	defer dev.Close()
+
+	assert.Nil(t, dev.SetLED(LED_NUML, true))
+	written := fake.Written()
+	assert.Equal(t, 2, len(written))
+	assert.Equal(t, uint16(EV_LED), written[0].Type)
+
+	leds, err := dev.LEDs()
+	assert.Nil(t, err)
+	assert.True(t, leds[LED_NUML])
+
+	id, err := dev.UploadRumble(RumbleEffect{ID: ffEffectNewID, Strong: 0x8000})
+	assert.Nil(t, err)
+	assert.Equal(t, int16(0), id)
+	assert.Nil(t, dev.EraseEffect(id))
+	assert.NotNil(t, dev.EraseEffect(id))
+}
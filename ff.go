@@ -0,0 +1,121 @@
+package inputeventsubsystem
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+const (
+	FF_STATUS_STOPPED = 0x00
+	FF_STATUS_PLAYING = 0x01
+	FF_STATUS_MAX     = 0x01
+	FF_RUMBLE         = 0x50
+	FF_PERIODIC       = 0x51
+	FF_CONSTANT       = 0x52
+	FF_SPRING         = 0x53
+	FF_FRICTION       = 0x54
+	FF_DAMPER         = 0x55
+	FF_INERTIA        = 0x56
+	FF_RAMP           = 0x57
+	FF_SQUARE         = 0x58
+	FF_TRIANGLE       = 0x59
+	FF_SINE           = 0x5a
+	FF_SAW_UP         = 0x5b
+	FF_SAW_DOWN       = 0x5c
+	FF_CUSTOM         = 0x5d
+	FF_GAIN           = 0x60
+	FF_AUTOCENTER     = 0x61
+	FF_MAX_EFFECTS    = FF_GAIN
+	FF_MAX            = 0x7f
+)
+
+var FFCodesString = map[uint16]string{
+	FF_RUMBLE:     "FF_RUMBLE",
+	FF_PERIODIC:   "FF_PERIODIC",
+	FF_CONSTANT:   "FF_CONSTANT",
+	FF_SPRING:     "FF_SPRING",
+	FF_FRICTION:   "FF_FRICTION",
+	FF_DAMPER:     "FF_DAMPER",
+	FF_INERTIA:    "FF_INERTIA",
+	FF_RAMP:       "FF_RAMP",
+	FF_SQUARE:     "FF_SQUARE",
+	FF_TRIANGLE:   "FF_TRIANGLE",
+	FF_SINE:       "FF_SINE",
+	FF_SAW_UP:     "FF_SAW_UP",
+	FF_SAW_DOWN:   "FF_SAW_DOWN",
+	FF_CUSTOM:     "FF_CUSTOM",
+	FF_GAIN:       "FF_GAIN",
+	FF_AUTOCENTER: "FF_AUTOCENTER",
+}
+
+// struct ff_effect offsets, the union always start at 16 whatever the word size
+const (
+	ffEffectTypeOffset     = 0
+	ffEffectIDOffset       = 2
+	ffEffectDirOffset      = 4
+	ffEffectLengthOffset   = 10
+	ffEffectDelayOffset    = 12
+	ffEffectUnionOffset    = 16
+	ffEffectNewID          = -1
+	ffEffectMaxReplayValue = 0xffff
+)
+
+type RumbleEffect struct {
+	ID     int16 // -1 to let the kernel allocate a new slot
+	Strong uint16
+	Weak   uint16
+	Length time.Duration
+	Delay  time.Duration
+}
+
+func replayMilliseconds(d time.Duration) uint16 {
+	ms := d.Milliseconds()
+	if ms < 0 {
+		return 0
+	}
+	if ms > ffEffectMaxReplayValue {
+		return ffEffectMaxReplayValue
+	}
+	return uint16(ms)
+}
+
+func (r *RumbleEffect) Pack() []byte {
+	var data []byte = make([]byte, FF_EFFECT_SIZE)
+
+	binary.NativeEndian.PutUint16(data[ffEffectTypeOffset:], FF_RUMBLE)
+	binary.NativeEndian.PutUint16(data[ffEffectIDOffset:], uint16(r.ID))
+	binary.NativeEndian.PutUint16(data[ffEffectLengthOffset:], replayMilliseconds(r.Length))
+	binary.NativeEndian.PutUint16(data[ffEffectDelayOffset:], replayMilliseconds(r.Delay))
+	binary.NativeEndian.PutUint16(data[ffEffectUnionOffset:], r.Strong)
+	binary.NativeEndian.PutUint16(data[ffEffectUnionOffset+2:], r.Weak)
+	return data
+}
+
+// UploadRumble upload or update (if effect.ID is not -1) a rumble effect and return the slot id allocated by the kernel
+func (dev *Device) UploadRumble(effect RumbleEffect) (int16, error) {
+
+	data := effect.Pack()
+
+	if err := dev.backend.UploadEffect(data); err != nil {
+		return effect.ID, dev.wrapError(OpIoctl, nil, err)
+	}
+
+	return int16(binary.NativeEndian.Uint16(data[ffEffectIDOffset:])), nil
+}
+
+func (dev *Device) PlayEffect(id int16, count int32) error {
+	return dev.WriteEvent(EV_FF, uint16(id), count)
+}
+
+func (dev *Device) StopEffect(id int16) error {
+	return dev.WriteEvent(EV_FF, uint16(id), 0)
+}
+
+func (dev *Device) EraseEffect(id int16) error {
+	return dev.wrapError(OpIoctl, nil, dev.backend.EraseEffect(id))
+}
+
+// SetFFGain set the global force feedback gain (0-0xffff)
+func (dev *Device) SetFFGain(gain uint16) error {
+	return dev.WriteEvent(EV_FF, FF_GAIN, int32(gain))
+}
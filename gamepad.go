@@ -0,0 +1,483 @@
+package inputeventsubsystem
+
+import (
+	"errors"
+	"sync"
+)
+
+// GamepadButton is a button of the standard layout. The face buttons are positional like SDL:
+// A is the bottom one, B the right one, X the left one and Y the top one, whatever their label
+type GamepadButton int
+
+const (
+	GamepadButtonA GamepadButton = iota
+	GamepadButtonB
+	GamepadButtonX
+	GamepadButtonY
+	GamepadButtonLeftShoulder
+	GamepadButtonRightShoulder
+	GamepadButtonLeftStick
+	GamepadButtonRightStick
+	GamepadButtonSelect
+	GamepadButtonStart
+	GamepadButtonGuide
+	GamepadButtonDpadUp
+	GamepadButtonDpadDown
+	GamepadButtonDpadLeft
+	GamepadButtonDpadRight
+	GamepadButtonCount
+)
+
+// GamepadAxis is an axis of the standard layout. The sticks go from -1 to 1 (up and left are negative),
+// the triggers from 0 to 1
+type GamepadAxis int
+
+const (
+	GamepadAxisLeftX GamepadAxis = iota
+	GamepadAxisLeftY
+	GamepadAxisRightX
+	GamepadAxisRightY
+	GamepadAxisLeftTrigger
+	GamepadAxisRightTrigger
+	GamepadAxisCount
+)
+
+var gamepadButtonString = map[GamepadButton]string{
+	GamepadButtonA:             "a",
+	GamepadButtonB:             "b",
+	GamepadButtonX:             "x",
+	GamepadButtonY:             "y",
+	GamepadButtonLeftShoulder:  "leftshoulder",
+	GamepadButtonRightShoulder: "rightshoulder",
+	GamepadButtonLeftStick:     "leftstick",
+	GamepadButtonRightStick:    "rightstick",
+	GamepadButtonSelect:        "back",
+	GamepadButtonStart:         "start",
+	GamepadButtonGuide:         "guide",
+	GamepadButtonDpadUp:        "dpup",
+	GamepadButtonDpadDown:      "dpdown",
+	GamepadButtonDpadLeft:      "dpleft",
+	GamepadButtonDpadRight:     "dpright",
+}
+
+var gamepadAxisString = map[GamepadAxis]string{
+	GamepadAxisLeftX:        "leftx",
+	GamepadAxisLeftY:        "lefty",
+	GamepadAxisRightX:       "rightx",
+	GamepadAxisRightY:       "righty",
+	GamepadAxisLeftTrigger:  "lefttrigger",
+	GamepadAxisRightTrigger: "righttrigger",
+}
+
+func (b GamepadButton) String() string {
+	return gamepadButtonString[b]
+}
+
+func (a GamepadAxis) String() string {
+	return gamepadAxisString[a]
+}
+
+// GamepadMapping translate the evdev codes of a controller to the standard layout.
+// ABS_HAT0X and ABS_HAT0Y drive the dpad unless they are mapped in Axes
+type GamepadMapping struct {
+	Name     string
+	Buttons  map[int]GamepadButton // EV_KEY code
+	Axes     map[int]GamepadAxis   // EV_ABS code
+	Triggers map[int]GamepadAxis   // EV_KEY code of the digital triggers, pressed is a full trigger
+}
+
+type GamepadID struct {
+	VendorID  uint16
+	ProductID uint16
+}
+
+var gamepadDpadButtons = map[int]GamepadButton{
+	BTN_DPAD_UP:    GamepadButtonDpadUp,
+	BTN_DPAD_DOWN:  GamepadButtonDpadDown,
+	BTN_DPAD_LEFT:  GamepadButtonDpadLeft,
+	BTN_DPAD_RIGHT: GamepadButtonDpadRight,
+}
+
+func gamepadButtons(buttons map[int]GamepadButton) map[int]GamepadButton {
+	for code, button := range gamepadDpadButtons {
+		buttons[code] = button
+	}
+	return buttons
+}
+
+// standardGamepadButtons return a new map of the kernel gamepad API buttons, each mapping own its maps
+func standardGamepadButtons() map[int]GamepadButton {
+	return gamepadButtons(map[int]GamepadButton{
+		BTN_SOUTH:  GamepadButtonA,
+		BTN_EAST:   GamepadButtonB,
+		BTN_WEST:   GamepadButtonX,
+		BTN_NORTH:  GamepadButtonY,
+		BTN_TL:     GamepadButtonLeftShoulder,
+		BTN_TR:     GamepadButtonRightShoulder,
+		BTN_THUMBL: GamepadButtonLeftStick,
+		BTN_THUMBR: GamepadButtonRightStick,
+		BTN_SELECT: GamepadButtonSelect,
+		BTN_START:  GamepadButtonStart,
+		BTN_MODE:   GamepadButtonGuide,
+	})
+}
+
+func standardGamepadAxes() map[int]GamepadAxis {
+	return map[int]GamepadAxis{
+		ABS_X:  GamepadAxisLeftX,
+		ABS_Y:  GamepadAxisLeftY,
+		ABS_RX: GamepadAxisRightX,
+		ABS_RY: GamepadAxisRightY,
+		ABS_Z:  GamepadAxisLeftTrigger,
+		ABS_RZ: GamepadAxisRightTrigger,
+	}
+}
+
+// StandardGamepadMapping follow the kernel gamepad API (Documentation/input/gamepad.rst), it is used for the unknown controllers
+var StandardGamepadMapping = GamepadMapping{
+	Name:     "Standard gamepad",
+	Buttons:  standardGamepadButtons(),
+	Axes:     standardGamepadAxes(),
+	Triggers: map[int]GamepadAxis{},
+}
+
+// XboxGamepadMapping is the layout of xpad, that report the X and Y buttons as BTN_X and BTN_Y
+var XboxGamepadMapping = GamepadMapping{
+	Name: "Xbox controller",
+	Buttons: gamepadButtons(map[int]GamepadButton{
+		BTN_A:      GamepadButtonA,
+		BTN_B:      GamepadButtonB,
+		BTN_X:      GamepadButtonX,
+		BTN_Y:      GamepadButtonY,
+		BTN_TL:     GamepadButtonLeftShoulder,
+		BTN_TR:     GamepadButtonRightShoulder,
+		BTN_THUMBL: GamepadButtonLeftStick,
+		BTN_THUMBR: GamepadButtonRightStick,
+		BTN_SELECT: GamepadButtonSelect,
+		BTN_START:  GamepadButtonStart,
+		BTN_MODE:   GamepadButtonGuide,
+	}),
+	Axes:     standardGamepadAxes(),
+	Triggers: map[int]GamepadAxis{},
+}
+
+// PlayStationGamepadMapping is the layout of hid-sony and hid-playstation: cross, circle, square, triangle,
+// share and options are A, B, X, Y, select and start
+var PlayStationGamepadMapping = GamepadMapping{
+	Name:     "PlayStation controller",
+	Buttons:  standardGamepadButtons(),
+	Axes:     standardGamepadAxes(),
+	Triggers: map[int]GamepadAxis{},
+}
+
+// NintendoGamepadMapping is the layout of hid-nintendo: the buttons are positional (the B label is A),
+// ZL and ZR are digital, minus, plus and capture are select, start and nothing
+var NintendoGamepadMapping = GamepadMapping{
+	Name:    "Nintendo controller",
+	Buttons: standardGamepadButtons(),
+	Axes: map[int]GamepadAxis{
+		ABS_X:  GamepadAxisLeftX,
+		ABS_Y:  GamepadAxisLeftY,
+		ABS_RX: GamepadAxisRightX,
+		ABS_RY: GamepadAxisRightY,
+	},
+	Triggers: map[int]GamepadAxis{
+		BTN_TL2: GamepadAxisLeftTrigger,
+		BTN_TR2: GamepadAxisRightTrigger,
+	},
+}
+
+// GamepadMappings are the built-in mappings by vendor and product
+var GamepadMappings = map[GamepadID]GamepadMapping{
+	{0x045e, 0x028e}: XboxGamepadMapping,        // Xbox 360
+	{0x045e, 0x028f}: XboxGamepadMapping,        // Xbox 360 wireless
+	{0x045e, 0x0719}: XboxGamepadMapping,        // Xbox 360 wireless receiver
+	{0x045e, 0x02d1}: XboxGamepadMapping,        // Xbox One
+	{0x045e, 0x02dd}: XboxGamepadMapping,        // Xbox One (2015)
+	{0x045e, 0x02ea}: XboxGamepadMapping,        // Xbox One S
+	{0x045e, 0x0b12}: XboxGamepadMapping,        // Xbox Series X|S
+	{0x045e, 0x0b13}: XboxGamepadMapping,        // Xbox Series X|S bluetooth
+	{0x054c, 0x0268}: PlayStationGamepadMapping, // DualShock 3
+	{0x054c, 0x05c4}: PlayStationGamepadMapping, // DualShock 4
+	{0x054c, 0x09cc}: PlayStationGamepadMapping, // DualShock 4 (2nd generation)
+	{0x054c, 0x0ba0}: PlayStationGamepadMapping, // DualShock 4 wireless adapter
+	{0x054c, 0x0ce6}: PlayStationGamepadMapping, // DualSense
+	{0x054c, 0x0df2}: PlayStationGamepadMapping, // DualSense Edge
+	{0x057e, 0x2009}: NintendoGamepadMapping,    // Switch Pro controller
+}
+
+// LookupGamepadMapping return the built-in mapping of the controller, StandardGamepadMapping if it is unknown
+func LookupGamepadMapping(vendor uint16, product uint16) (GamepadMapping, bool) {
+	if mapping, ok := GamepadMappings[GamepadID{vendor, product}]; ok {
+		return mapping, true
+	}
+	return StandardGamepadMapping, false
+}
+
+type GamepadState struct {
+	Buttons [GamepadButtonCount]bool
+	Axes    [GamepadAxisCount]float64
+}
+
+// gamepadBinding route an evdev key or axis to a button or an axis of the layout
+type gamepadBinding struct {
+	half   int  // input half of an axis: 1 the positive one, -1 the negative one, 0 the whole axis
+	invert bool // input axis inverted
+	isAxis bool
+	button GamepadButton
+	axis   GamepadAxis
+	sign   int // output half of a stick: 1 or -1, 0 the whole stick
+}
+
+func (b gamepadBinding) trigger() bool {
+	return b.isAxis && (b.axis == GamepadAxisLeftTrigger || b.axis == GamepadAxisRightTrigger)
+}
+
+// apply the input value, from 0 to 1 for the keys and the axis halves, from -1 to 1 (bipolar) for the whole axes
+func (b gamepadBinding) apply(s *GamepadState, value float64, bipolar bool) {
+	if !b.isAxis {
+		s.Buttons[b.button] = value > 0.5
+		return
+	}
+
+	switch {
+	case b.trigger() && bipolar, b.sign != 0 && bipolar:
+		value = (value + 1) / 2
+	case !b.trigger() && b.sign == 0 && !bipolar:
+		value = 2*value - 1
+	}
+
+	if b.sign != 0 {
+		value *= float64(b.sign)
+	}
+	s.Axes[b.axis] = value
+}
+
+// Gamepad decode the events of a controller in the standard layout. The events read from the device are given
+// to Update, the state is published at each SYN_REPORT.
+// Mapping is the layout the gamepad was built from, only its Name is set for the SDL mappings
+type Gamepad struct {
+	AxisDevice *AxisDevice
+	Mapping    GamepadMapping
+	keys       map[int][]gamepadBinding
+	abs        map[int][]gamepadBinding
+	lock       sync.Mutex
+	pending    GamepadState
+	state      GamepadState
+	dropped    bool
+}
+
+func newGamepad(axis *AxisDevice, mapping GamepadMapping) *Gamepad {
+	return &Gamepad{
+		AxisDevice: axis,
+		Mapping:    mapping,
+		keys:       make(map[int][]gamepadBinding),
+		abs:        make(map[int][]gamepadBinding),
+	}
+}
+
+func NewGamepad(axis *AxisDevice, mapping GamepadMapping) *Gamepad {
+	g := newGamepad(axis, mapping)
+
+	for code, button := range mapping.Buttons {
+		g.keys[code] = append(g.keys[code], gamepadBinding{button: button})
+	}
+	for code, axis := range mapping.Triggers {
+		g.keys[code] = append(g.keys[code], gamepadBinding{isAxis: true, axis: axis})
+	}
+	for code, axis := range mapping.Axes {
+		g.abs[code] = append(g.abs[code], gamepadBinding{isAxis: true, axis: axis})
+	}
+
+	if _, ok := mapping.Axes[ABS_HAT0X]; !ok {
+		g.abs[ABS_HAT0X] = []gamepadBinding{{half: -1, button: GamepadButtonDpadLeft}, {half: 1, button: GamepadButtonDpadRight}}
+	}
+	if _, ok := mapping.Axes[ABS_HAT0Y]; !ok {
+		g.abs[ABS_HAT0Y] = []gamepadBinding{{half: -1, button: GamepadButtonDpadUp}, {half: 1, button: GamepadButtonDpadDown}}
+	}
+	return g
+}
+
+// gamepadAxisDevice return the axes of the device with their dead zone, empty for the pads with buttons only
+func (e *Device) gamepadAxisDevice() (*AxisDevice, error) {
+	axis, err := e.AxisDevice(true, -1)
+	if errors.Is(err, ErrDeviceHasNoAxis) {
+		return CreateAxisDeviceFromAbsInfo(e, nil, true, -1), nil
+	}
+	return axis, err
+}
+
+// Gamepad open the standard layout of the device with its built-in mapping, the sticks use the dead zone of the device
+func (e *Device) Gamepad() (*Gamepad, error) {
+	axis, err := e.gamepadAxisDevice()
+	if err != nil {
+		return nil, err
+	}
+
+	mapping, _ := LookupGamepadMapping(e.VendorID, e.ProductID)
+	g := NewGamepad(axis, mapping)
+	return g, g.Sync()
+}
+
+// rawRange return the position of the value in the axis range, from 0 to 1
+func (g *Gamepad) rawRange(code int, value int32) float64 {
+	min, max := g.AxisDevice.GetRange(code)
+	if max == min {
+		return 0
+	}
+
+	t := float64(value-min) / float64(max-min)
+	if t < 0 {
+		return 0
+	}
+	if t > 1 {
+		return 1
+	}
+	return t
+}
+
+// normalise the axis value from -1 to 1, the hats are read as a direction sign
+func (g *Gamepad) normalise(code int, value int32) float64 {
+	if code >= ABS_HAT0X && code <= ABS_HAT3Y {
+		switch {
+		case value < 0:
+			return -1
+		case value > 0:
+			return 1
+		}
+		return 0
+	}
+	return float64(g.AxisDevice.CorrectAxis(code, value)) / 32767
+}
+
+func (g *Gamepad) updateAbs(code int, value int32) {
+	for _, b := range g.abs[code] {
+
+		// a whole axis driving a trigger use the raw range, the dead zone is centered
+		if b.trigger() && b.half == 0 {
+			t := g.rawRange(code, value)
+			if b.invert {
+				t = 1 - t
+			}
+			b.apply(&g.pending, t, false)
+			continue
+		}
+
+		v := g.normalise(code, value)
+		if b.invert {
+			v = -v
+		}
+
+		if b.half == 0 {
+			b.apply(&g.pending, v, true)
+			continue
+		}
+
+		v *= float64(b.half)
+		if v < 0 {
+			v = 0
+		}
+		b.apply(&g.pending, v, false)
+	}
+}
+
+func (g *Gamepad) updateKey(code int, pressed bool) {
+	var value float64
+	if pressed {
+		value = 1
+	}
+
+	for _, b := range g.keys[code] {
+		b.apply(&g.pending, value, false)
+	}
+}
+
+// Update apply an event read from the device, it return true when a frame is complete and State changed.
+// After a SYN_DROPPED the events are ignored until the next SYN_REPORT, then the state is read again with Sync
+func (g *Gamepad) Update(ev *Event) bool {
+	g.lock.Lock()
+
+	switch {
+	case ev.Type == EV_SYN && ev.Code == SYN_DROPPED:
+		g.dropped = true
+
+	case ev.Type == EV_SYN && ev.Code == SYN_REPORT:
+		if g.dropped {
+			g.lock.Unlock()
+			return g.Sync() == nil
+		}
+		changed := g.state != g.pending
+		g.state = g.pending
+		g.lock.Unlock()
+		return changed
+
+	case g.dropped:
+
+	case ev.Type == EV_KEY && ev.Value != 2:
+		g.updateKey(int(ev.Code), ev.Value != 0)
+
+	case ev.Type == EV_ABS:
+		g.updateAbs(int(ev.Code), ev.Value)
+	}
+
+	g.lock.Unlock()
+	return false
+}
+
+// Sync read the current key and axis states from the device
+func (g *Gamepad) Sync() error {
+	dev := g.AxisDevice.Device
+
+	keybits, err := dev.KeysState()
+	if err != nil {
+		return err
+	}
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	// every bound key is applied, a released key may share its button with a pressed one
+	g.pending = GamepadState{}
+	for code := range g.keys {
+		g.updateKey(code, code/8 < len(keybits) && keybits[code/8]&(1<<uint(code%8)) != 0)
+	}
+
+	for code := range dev.Capabilities[EV_ABS] {
+		a, err := dev.AbsState(code)
+		if err != nil {
+			return err
+		}
+		g.updateAbs(code, a.Value)
+	}
+
+	g.state = g.pending
+	g.dropped = false
+	return nil
+}
+
+// State return the state at the last complete frame
+func (g *Gamepad) State() GamepadState {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return g.state
+}
+
+func (g *Gamepad) Button(button GamepadButton) bool {
+	return g.State().Buttons[button]
+}
+
+func (g *Gamepad) Axis(axis GamepadAxis) float64 {
+	return g.State().Axes[axis]
+}
+
+func (g *Gamepad) Read() chan []*Event {
+	return g.AxisDevice.Read()
+}
+
+func (g *Gamepad) ReadDone(events []*Event) {
+	g.AxisDevice.ReadDone(events)
+}
+
+func (g *Gamepad) Close() error {
+	return g.AxisDevice.Close()
+}
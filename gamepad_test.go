@@ -0,0 +1,177 @@
+package inputeventsubsystem
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func fakeXboxPad() *FakeDevice {
+	stick := AbsInfo{Minimum: -32768, Maximum: 32767}
+	trigger := AbsInfo{Minimum: 0, Maximum: 255}
+
+	return NewFakeDevice(EvemuDevice{
+		Name:      "Microsoft X-Box 360 pad",
+		Bus:       0x03,
+		VendorID:  0x045e,
+		ProductID: 0x028e,
+		Version:   0x0110,
+		Codes: map[int][]int{
+			EV_SYN: {SYN_REPORT},
+			EV_KEY: {BTN_A, BTN_B, BTN_X, BTN_Y, BTN_TL, BTN_TR, BTN_SELECT, BTN_START, BTN_MODE, BTN_THUMBL, BTN_THUMBR},
+			EV_ABS: {ABS_X, ABS_Y, ABS_Z, ABS_RX, ABS_RY, ABS_RZ, ABS_HAT0X, ABS_HAT0Y},
+		},
+		Absinfos: map[int]AbsInfo{
+			ABS_X: stick, ABS_Y: stick, ABS_RX: stick, ABS_RY: stick,
+			ABS_Z: trigger, ABS_RZ: trigger,
+			ABS_HAT0X: {Minimum: -1, Maximum: 1}, ABS_HAT0Y: {Minimum: -1, Maximum: 1},
+		},
+	})
+}
+
+func TestGamepadMapping(t *testing.T) {
+	mapping, ok := LookupGamepadMapping(0x054c, 0x0ce6)
+	assert.True(t, ok)
+	assert.Equal(t, GamepadButtonY, mapping.Buttons[BTN_NORTH])
+
+	mapping, ok = LookupGamepadMapping(0x045e, 0x028e)
+	assert.True(t, ok)
+	assert.Equal(t, GamepadButtonX, mapping.Buttons[BTN_X])
+
+	mapping, ok = LookupGamepadMapping(0x1234, 0x5678)
+	assert.False(t, ok)
+	assert.Equal(t, "Standard gamepad", mapping.Name)
+	assert.Equal(t, "dpup", GamepadButtonDpadUp.String())
+
+	// the mappings do not share their maps
+	PlayStationGamepadMapping.Buttons[BTN_NORTH] = GamepadButtonX
+	defer func() { PlayStationGamepadMapping.Buttons[BTN_NORTH] = GamepadButtonY }()
+	assert.Equal(t, GamepadButtonY, StandardGamepadMapping.Buttons[BTN_NORTH])
+	assert.Equal(t, GamepadButtonY, NintendoGamepadMapping.Buttons[BTN_NORTH])
+	XboxGamepadMapping.Axes[ABS_X] = GamepadAxisRightX
+	defer func() { XboxGamepadMapping.Axes[ABS_X] = GamepadAxisLeftX }()
+	assert.Equal(t, GamepadAxisLeftX, StandardGamepadMapping.Axes[ABS_X])
+	assert.Equal(t, GamepadAxisLeftX, PlayStationGamepadMapping.Axes[ABS_X])
+}
+
+func TestGamepad(t *testing.T) {
+	fake := fakeXboxPad()
+	fake.SetKey(BTN_START, true)
+
+	dev, err := OpenBackend("fake", fake)
+	assert.Nil(t, err)
+	defer dev.Close()
+
+	g, err := dev.Gamepad()
+	assert.Nil(t, err)
+	assert.Equal(t, "Xbox controller", g.Mapping.Name)
+	assert.True(t, g.Button(GamepadButtonStart))
+
+	frame := []Event{
+		{Type: EV_KEY, Code: BTN_X, Value: 1},
+		{Type: EV_ABS, Code: ABS_X, Value: 32767},
+		{Type: EV_ABS, Code: ABS_Y, Value: -32768},
+		{Type: EV_ABS, Code: ABS_RZ, Value: 255},
+		{Type: EV_ABS, Code: ABS_HAT0Y, Value: -1},
+	}
+	for i := range frame {
+		assert.False(t, g.Update(&frame[i]))
+	}
+
+	// nothing is published before the SYN_REPORT
+	assert.False(t, g.Button(GamepadButtonX))
+	assert.True(t, g.Update(&Event{Type: EV_SYN, Code: SYN_REPORT}))
+
+	state := g.State()
+	assert.True(t, state.Buttons[GamepadButtonX])
+	assert.True(t, state.Buttons[GamepadButtonDpadUp])
+	assert.False(t, state.Buttons[GamepadButtonDpadDown])
+	assert.InDelta(t, 1, state.Axes[GamepadAxisLeftX], 0.01)
+	assert.InDelta(t, -1, state.Axes[GamepadAxisLeftY], 0.01)
+	assert.InDelta(t, 1, state.Axes[GamepadAxisRightTrigger], 0.001)
+	assert.InDelta(t, 0, state.Axes[GamepadAxisLeftTrigger], 0.001)
+
+	// an unchanged frame is not reported
+	assert.False(t, g.Update(&Event{Type: EV_SYN, Code: SYN_REPORT}))
+
+	// after SYN_DROPPED the state is read back from the device
+	fake.Inject(Event{Type: EV_KEY, Code: BTN_X, Value: 0}, Event{Type: EV_ABS, Code: ABS_Z, Value: 255})
+	g.Update(&Event{Type: EV_SYN, Code: SYN_DROPPED})
+	g.Update(&Event{Type: EV_KEY, Code: BTN_B, Value: 1})
+	assert.True(t, g.Update(&Event{Type: EV_SYN, Code: SYN_REPORT}))
+	assert.False(t, g.Button(GamepadButtonX))
+	assert.False(t, g.Button(GamepadButtonB))
+	assert.InDelta(t, 1, g.Axis(GamepadAxisLeftTrigger), 0.001)
+}
+
+func TestGamepadDigitalTriggers(t *testing.T) {
+	g := NewGamepad(CreateAxisDeviceFromAbsInfo(nil, nil, true, -1), NintendoGamepadMapping)
+
+	g.Update(&Event{Type: EV_KEY, Code: BTN_TL2, Value: 1})
+	g.Update(&Event{Type: EV_KEY, Code: BTN_SOUTH, Value: 1})
+	g.Update(&Event{Type: EV_SYN, Code: SYN_REPORT})
+
+	assert.Equal(t, float64(1), g.Axis(GamepadAxisLeftTrigger))
+	assert.True(t, g.Button(GamepadButtonA))
+}
+
+func TestGamepadSync(t *testing.T) {
+	fake := NewFakeDevice(EvemuDevice{
+		Name:      "Nintendo Switch Pro Controller",
+		Bus:       0x03,
+		VendorID:  0x057e,
+		ProductID: 0x2009,
+		Codes: map[int][]int{
+			EV_SYN: {SYN_REPORT},
+			EV_KEY: {BTN_SOUTH, BTN_EAST, BTN_TL2, BTN_TR2},
+			EV_ABS: {ABS_X, ABS_Y},
+		},
+		Absinfos: map[int]AbsInfo{ABS_X: {Minimum: -32768, Maximum: 32767}, ABS_Y: {Minimum: -32768, Maximum: 32767}},
+	})
+	fake.SetKey(BTN_TL2, true)
+
+	dev, err := OpenBackend("fake", fake)
+	assert.Nil(t, err)
+	defer dev.Close()
+
+	g, err := dev.Gamepad()
+	assert.Nil(t, err)
+	assert.Equal(t, NintendoGamepadMapping.Name, g.Mapping.Name)
+	assert.Equal(t, float64(1), g.Axis(GamepadAxisLeftTrigger))
+
+	// the released keys are read back as released, the digital trigger too
+	g.Update(&Event{Type: EV_KEY, Code: BTN_SOUTH, Value: 1})
+	g.Update(&Event{Type: EV_SYN, Code: SYN_REPORT})
+	fake.Inject(Event{Type: EV_KEY, Code: BTN_TL2, Value: 0}, Event{Type: EV_KEY, Code: BTN_SOUTH, Value: 0})
+	g.Update(&Event{Type: EV_SYN, Code: SYN_DROPPED})
+	assert.True(t, g.Update(&Event{Type: EV_SYN, Code: SYN_REPORT}))
+	assert.Equal(t, float64(0), g.Axis(GamepadAxisLeftTrigger))
+	assert.False(t, g.Button(GamepadButtonA))
+}
+
+func TestGamepadButtonsOnly(t *testing.T) {
+	fake := NewFakeDevice(EvemuDevice{
+		Name:      "Retro pad",
+		Bus:       0x03,
+		VendorID:  0x1234,
+		ProductID: 0x0001,
+		Codes: map[int][]int{
+			EV_SYN: {SYN_REPORT},
+			EV_KEY: {BTN_SOUTH, BTN_EAST, BTN_START, BTN_DPAD_UP},
+		},
+	})
+	fake.SetKey(BTN_START, true)
+
+	dev, err := OpenBackend("fake", fake)
+	assert.Nil(t, err)
+	defer dev.Close()
+
+	g, err := dev.Gamepad()
+	assert.Nil(t, err)
+	assert.True(t, g.Button(GamepadButtonStart))
+
+	g.Update(&Event{Type: EV_KEY, Code: BTN_SOUTH, Value: 1})
+	assert.True(t, g.Update(&Event{Type: EV_SYN, Code: SYN_REPORT}))
+	assert.True(t, g.Button(GamepadButtonA))
+	assert.Equal(t, float64(0), g.Axis(GamepadAxisLeftX))
+}
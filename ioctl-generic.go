@@ -0,0 +1,6 @@
+//go:build !mips && !mipsle && !mips64 && !mips64le && !ppc64 && !ppc64le
+// +build !mips,!mipsle,!mips64,!mips64le,!ppc64,!ppc64le
+
+package inputeventsubsystem
+
+var nativeIoctlEncoding = ioctlEncodingGeneric
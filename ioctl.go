@@ -1,63 +1,20 @@
 package inputeventsubsystem
 
 import (
+	"runtime"
 	"syscall"
 	"unsafe"
 
 	"golang.org/x/sys/unix"
 )
 
-/*
-
-#include <linux/input.h>
-#include <stdlib.h>
-#include <stdio.h>
-
-
-static inline int eviocgname(int size)
-{
-return EVIOCGNAME(size);
-}
-
-static inline int eviocgphys(int size)
-{
-return EVIOCGPHYS(size);
-}
-
-static inline int eviocgbit(int min,int max)
-{
-return EVIOCGBIT(min,max);
-}
-
-
-static inline int eviocgabs(int type)
-{
-return EVIOCGABS(type);
-}
-
-static inline int eviockey(int size)
-{
-return EVIOCGKEY(size);
-}
-
-
-static inline int eviockeycode()
-{
-return EVIOCGKEYCODE;
-}
-
-static inline int eviocled(int size)
-{
-return EVIOCGLED(size);
-}
-
-
-*/
-import "C"
-
 const (
-	INPUT_NAME_LEN = 256
-	INPUT_PHY_LEN  = 256
+	INPUT_NAME_LEN       = 256
+	INPUT_PHY_LEN        = 256
+	INPUT_UNIQ_LEN       = 256
+	UINPUT_MAX_NAME_SIZE = 80
+	FF_EFFECT_SIZE       = sizeofFFEffect
+	KEYMAP_SIZE          = sizeofKeymapEntry
 )
 
 func ioctl(fd uintptr, name uintptr, data unsafe.Pointer) syscall.Errno {
@@ -68,7 +25,7 @@ func ioctl(fd uintptr, name uintptr, data unsafe.Pointer) syscall.Errno {
 func IoctlInputName(fd int) (string, error) {
 	var err error
 	var value [INPUT_NAME_LEN]byte
-	if errno := ioctl(uintptr(fd), uintptr(C.eviocgname(INPUT_NAME_LEN)), unsafe.Pointer(&value[0])); errno != 0 {
+	if errno := ioctl(uintptr(fd), EVIOCGNAME(INPUT_NAME_LEN), unsafe.Pointer(&value[0])); errno != 0 {
 		err = errno
 	}
 	return unix.ByteSliceToString(value[:]), err
@@ -78,7 +35,18 @@ func IoctlInputName(fd int) (string, error) {
 func IoctlInputPhys(fd int) (string, error) {
 	var err error
 	var value [INPUT_PHY_LEN]byte
-	if errno := ioctl(uintptr(fd), uintptr(C.eviocgphys(INPUT_NAME_LEN)), unsafe.Pointer(&value[0])); errno != 0 {
+	if errno := ioctl(uintptr(fd), EVIOCGPHYS(INPUT_PHY_LEN), unsafe.Pointer(&value[0])); errno != 0 {
+		err = errno
+	}
+
+	return unix.ByteSliceToString(value[:]), err
+
+}
+
+func IoctlInputUniq(fd int) (string, error) {
+	var err error
+	var value [INPUT_UNIQ_LEN]byte
+	if errno := ioctl(uintptr(fd), EVIOCGUNIQ(INPUT_UNIQ_LEN), unsafe.Pointer(&value[0])); errno != 0 {
 		err = errno
 	}
 
@@ -90,7 +58,7 @@ func IoctlInputVersion(fd int) (uint32, error) {
 
 	var version uint32
 	var err error
-	if errno := ioctl(uintptr(fd), C.EVIOCGVERSION, unsafe.Pointer(&version)); errno != 0 {
+	if errno := ioctl(uintptr(fd), EVIOCGVERSION, unsafe.Pointer(&version)); errno != 0 {
 		err = errno
 	}
 	return version, err
@@ -102,21 +70,29 @@ func IoctlInputID(fd int) (uint16, uint16, uint16, uint16, error) {
 	var ids [4]uint16
 
 	var err error
-	if errno := ioctl(uintptr(fd), C.EVIOCGID, unsafe.Pointer(&ids)); errno != 0 {
+	if errno := ioctl(uintptr(fd), EVIOCGID, unsafe.Pointer(&ids)); errno != 0 {
 		err = errno
 	}
 
-	return ids[C.ID_BUS], ids[C.ID_VENDOR], ids[C.ID_PRODUCT], ids[C.ID_VERSION], err
+	return ids[ID_BUS], ids[ID_VENDOR], ids[ID_PRODUCT], ids[ID_VERSION], err
 
 }
 
 func IoctlInputGrab(fd int, acquire bool) error {
 
 	if acquire {
-		return unix.IoctlSetInt(fd, C.EVIOCGRAB, 1)
+		return unix.IoctlSetInt(fd, uint(EVIOCGRAB), 1)
 	}
 
-	return unix.IoctlSetInt(fd, C.EVIOCGRAB, 0)
+	return unix.IoctlSetInt(fd, uint(EVIOCGRAB), 0)
+}
+
+func IoctlInputRevoke(fd int) error {
+	return unix.IoctlSetInt(fd, uint(EVIOCREVOKE), 0)
+}
+
+func IoctlInputClockID(fd int, clockid int) error {
+	return unix.IoctlSetPointerInt(fd, uint(EVIOCSCLOCKID), clockid)
 }
 
 func IoctlInputBit(fd int, min, max int) ([]byte, error) {
@@ -124,21 +100,40 @@ func IoctlInputBit(fd int, min, max int) ([]byte, error) {
 	var databits []byte = make([]byte, (max/8)+1)
 
 	var err error
-	if errno := ioctl(uintptr(fd), uintptr(C.eviocgbit(C.int(min), C.int(len(databits)))), unsafe.Pointer(&databits[0])); errno != 0 {
+	if errno := ioctl(uintptr(fd), EVIOCGBIT(min, len(databits)), unsafe.Pointer(&databits[0])); errno != 0 {
 		err = errno
 	}
 	return databits, err
 }
 
+func IoctlInputProp(fd int) ([]byte, error) {
+
+	var propbits []byte = make([]byte, (INPUT_PROP_MAX+1)/8)
+
+	var err error
+	if errno := ioctl(uintptr(fd), EVIOCGPROP(len(propbits)), unsafe.Pointer(&propbits[0])); errno != 0 {
+		err = errno
+	}
+	return propbits, err
+}
+
 func IoctlInputAbs(fd int, typeabs int) ([]byte, error) {
 	var absbits []byte = make([]byte, 24)
 	var err error
-	if errno := ioctl(uintptr(fd), uintptr(C.eviocgabs(C.int(typeabs))), unsafe.Pointer(&absbits[0])); errno != 0 {
+	if errno := ioctl(uintptr(fd), EVIOCGABS(typeabs), unsafe.Pointer(&absbits[0])); errno != 0 {
 		err = errno
 	}
 	return absbits, err
 }
 
+func IoctlSetInputAbs(fd int, typeabs int, absbits []byte) error {
+	var err error
+	if errno := ioctl(uintptr(fd), EVIOCSABS(typeabs), unsafe.Pointer(&absbits[0])); errno != 0 {
+		err = errno
+	}
+	return err
+}
+
 func IoctlInputKey(fd int) ([]byte, error) {
 
 	var sizekeybits int = (KEY_MAX + 1) / 8
@@ -146,7 +141,7 @@ func IoctlInputKey(fd int) ([]byte, error) {
 	var keybits []byte = make([]byte, sizekeybits)
 
 	var err error
-	if errno := ioctl(uintptr(fd), uintptr(C.eviockey(C.int(sizekeybits))), unsafe.Pointer(&keybits[0])); errno != 0 {
+	if errno := ioctl(uintptr(fd), EVIOCGKEY(sizekeybits), unsafe.Pointer(&keybits[0])); errno != 0 {
 		err = errno
 	}
 	return keybits, err
@@ -159,7 +154,7 @@ func IoctlLeds(fd int) ([]byte, error) {
 	var ledbits []byte = make([]byte, sizelledbits)
 
 	var err error
-	if errno := ioctl(uintptr(fd), uintptr(C.eviocled(C.int(sizelledbits))), unsafe.Pointer(&ledbits[0])); errno != 0 {
+	if errno := ioctl(uintptr(fd), EVIOCGLED(sizelledbits), unsafe.Pointer(&ledbits[0])); errno != 0 {
 		err = errno
 	}
 	return ledbits, err
@@ -167,12 +162,140 @@ func IoctlLeds(fd int) ([]byte, error) {
 
 func IoctlGetScanCode(fd int, key uint16) (uint16, error) {
 
-	var scankeys []uint16 = make([]uint16, 4)
+	var scankeys [2]uint32
+
+	scankeys[0] = uint32(key)
+	var err error
+	if errno := ioctl(uintptr(fd), EVIOCGKEYCODE, unsafe.Pointer(&scankeys[0])); errno != 0 {
+		err = errno
+	}
+	return uint16(scankeys[1]), err
+}
+
+func IoctlSetKeycode(fd int, scancode uint32, keycode uint32) error {
+
+	var scankeys [2]uint32 = [2]uint32{scancode, keycode}
+
+	var err error
+	if errno := ioctl(uintptr(fd), EVIOCSKEYCODE, unsafe.Pointer(&scankeys[0])); errno != 0 {
+		err = errno
+	}
+	return err
+}
+
+func IoctlGetKeycodeV2(fd int, entry []byte) error {
 
-	scankeys[0] = key
 	var err error
-	if errno := ioctl(uintptr(fd), C.EVIOCGKEYCODE, unsafe.Pointer(&scankeys[0])); errno != 0 {
+	if errno := ioctl(uintptr(fd), EVIOCGKEYCODE_V2, unsafe.Pointer(&entry[0])); errno != 0 {
 		err = errno
 	}
-	return scankeys[1], err
+	return err
+}
+
+func IoctlSetKeycodeV2(fd int, entry []byte) error {
+
+	var err error
+	if errno := ioctl(uintptr(fd), EVIOCSKEYCODE_V2, unsafe.Pointer(&entry[0])); errno != 0 {
+		err = errno
+	}
+	return err
+}
+
+func IoctlUploadEffect(fd int, effect []byte) error {
+
+	var err error
+	if errno := ioctl(uintptr(fd), EVIOCSFF, unsafe.Pointer(&effect[0])); errno != 0 {
+		err = errno
+	}
+	return err
+}
+
+func IoctlEraseEffect(fd int, id int16) error {
+	return unix.IoctlSetInt(fd, uint(EVIOCRMFF), int(id))
+}
+
+func IoctlGetRepeat(fd int) (uint32, uint32, error) {
+
+	var rep [2]uint32
+	var err error
+	if errno := ioctl(uintptr(fd), EVIOCGREP, unsafe.Pointer(&rep)); errno != 0 {
+		err = errno
+	}
+	return rep[REP_DELAY], rep[REP_PERIOD], err
+}
+
+func IoctlSetRepeat(fd int, delay uint32, period uint32) error {
+
+	var rep [2]uint32
+	rep[REP_DELAY] = delay
+	rep[REP_PERIOD] = period
+
+	var err error
+	if errno := ioctl(uintptr(fd), EVIOCSREP, unsafe.Pointer(&rep)); errno != 0 {
+		err = errno
+	}
+	return err
+}
+
+func IoctlUinputSetBit(fd int, request uintptr, code int) error {
+	return unix.IoctlSetInt(fd, uint(request), code)
+}
+
+// setup is a packed struct uinput_setup
+func IoctlUinputSetup(fd int, setup []byte) error {
+
+	var err error
+	if errno := ioctl(uintptr(fd), UI_DEV_SETUP, unsafe.Pointer(&setup[0])); errno != 0 {
+		err = errno
+	}
+	return err
+}
+
+// abssetup is a packed struct uinput_abs_setup
+func IoctlUinputAbsSetup(fd int, abssetup []byte) error {
+
+	var err error
+	if errno := ioctl(uintptr(fd), UI_ABS_SETUP, unsafe.Pointer(&abssetup[0])); errno != 0 {
+		err = errno
+	}
+	return err
+}
+
+func IoctlUinputCreate(fd int) error {
+	return unix.IoctlSetInt(fd, uint(UI_DEV_CREATE), 0)
+}
+
+func IoctlUinputDestroy(fd int) error {
+	return unix.IoctlSetInt(fd, uint(UI_DEV_DESTROY), 0)
+}
+
+// struct input_mask
+type inputMask struct {
+	Type      uint32
+	CodesSize uint32
+	CodesPtr  uint64
+}
+
+func IoctlGetEventMask(fd int, evtype int, codebits []byte) error {
+
+	mask := inputMask{Type: uint32(evtype), CodesSize: uint32(len(codebits)), CodesPtr: uint64(uintptr(unsafe.Pointer(&codebits[0])))}
+
+	var err error
+	if errno := ioctl(uintptr(fd), EVIOCGMASK, unsafe.Pointer(&mask)); errno != 0 {
+		err = errno
+	}
+	runtime.KeepAlive(codebits)
+	return err
+}
+
+func IoctlSetEventMask(fd int, evtype int, codebits []byte) error {
+
+	mask := inputMask{Type: uint32(evtype), CodesSize: uint32(len(codebits)), CodesPtr: uint64(uintptr(unsafe.Pointer(&codebits[0])))}
+
+	var err error
+	if errno := ioctl(uintptr(fd), EVIOCSMASK, unsafe.Pointer(&mask)); errno != 0 {
+		err = errno
+	}
+	runtime.KeepAlive(codebits)
+	return err
 }
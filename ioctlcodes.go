@@ -0,0 +1,146 @@
+package inputeventsubsystem
+
+import "unsafe"
+
+// ioctlEncoding describe how an architecture pack direction, size, type and number in an ioctl request (asm/ioctl.h)
+type ioctlEncoding struct {
+	sizeBits uint
+	none     uintptr
+	write    uintptr
+	read     uintptr
+}
+
+var (
+	// x86, arm, arm64, riscv, s390, loongarch
+	ioctlEncodingGeneric = ioctlEncoding{sizeBits: 14, none: 0, write: 1, read: 2}
+	// powerpc, mips and sparc
+	ioctlEncodingPowerPC = ioctlEncoding{sizeBits: 13, none: 1, write: 4, read: 2}
+)
+
+const (
+	_IOC_NRSHIFT   = 0
+	_IOC_TYPESHIFT = 8
+	_IOC_SIZESHIFT = 16
+)
+
+func (e ioctlEncoding) ioc(dir uintptr, typ uintptr, nr uintptr, size uintptr) uintptr {
+	return dir<<(_IOC_SIZESHIFT+e.sizeBits) | size<<_IOC_SIZESHIFT | typ<<_IOC_TYPESHIFT | nr<<_IOC_NRSHIFT
+}
+
+func _IOC(dir uintptr, typ uintptr, nr uintptr, size uintptr) uintptr {
+	return nativeIoctlEncoding.ioc(dir, typ, nr, size)
+}
+
+func _IO(typ uintptr, nr uintptr) uintptr {
+	return _IOC(nativeIoctlEncoding.none, typ, nr, 0)
+}
+
+func _IOR(typ uintptr, nr uintptr, size uintptr) uintptr {
+	return _IOC(nativeIoctlEncoding.read, typ, nr, size)
+}
+
+func _IOW(typ uintptr, nr uintptr, size uintptr) uintptr {
+	return _IOC(nativeIoctlEncoding.write, typ, nr, size)
+}
+
+const (
+	ID_BUS     = 0
+	ID_VENDOR  = 1
+	ID_PRODUCT = 2
+	ID_VERSION = 3
+)
+
+// size of the kernel structures used by the evdev ioctls
+const (
+	sizeofInt           = 4
+	sizeofInputID       = 8
+	sizeofAbsInfo       = 24
+	sizeofRepeat        = 8
+	sizeofKeycode       = 8
+	sizeofKeymapEntry   = 40
+	sizeofInputMask     = 16
+	sizeofFFEffectUnion = 24 + int(unsafe.Sizeof(uintptr(0))) // ff_periodic_effect end with a pointer
+	sizeofFFEffect      = 16 + sizeofFFEffectUnion
+	sizeofUinputSetup   = sizeofInputID + UINPUT_MAX_NAME_SIZE + 4
+	sizeofUinputAbs     = 4 + sizeofAbsInfo
+)
+
+var (
+	EVIOCGVERSION    = _IOR('E', 0x01, sizeofInt)
+	EVIOCGID         = _IOR('E', 0x02, sizeofInputID)
+	EVIOCGREP        = _IOR('E', 0x03, sizeofRepeat)
+	EVIOCSREP        = _IOW('E', 0x03, sizeofRepeat)
+	EVIOCGKEYCODE    = _IOR('E', 0x04, sizeofKeycode)
+	EVIOCGKEYCODE_V2 = _IOR('E', 0x04, sizeofKeymapEntry)
+	EVIOCSKEYCODE    = _IOW('E', 0x04, sizeofKeycode)
+	EVIOCSKEYCODE_V2 = _IOW('E', 0x04, sizeofKeymapEntry)
+	EVIOCSFF         = _IOW('E', 0x80, uintptr(sizeofFFEffect))
+	EVIOCRMFF        = _IOW('E', 0x81, sizeofInt)
+	EVIOCGEFFECTS    = _IOR('E', 0x84, sizeofInt)
+	EVIOCGRAB        = _IOW('E', 0x90, sizeofInt)
+	EVIOCREVOKE      = _IOW('E', 0x91, sizeofInt)
+	EVIOCGMASK       = _IOR('E', 0x92, sizeofInputMask)
+	EVIOCSMASK       = _IOW('E', 0x93, sizeofInputMask)
+	EVIOCSCLOCKID    = _IOW('E', 0xa0, sizeofInt)
+)
+
+var (
+	UI_DEV_CREATE  = _IO('U', 1)
+	UI_DEV_DESTROY = _IO('U', 2)
+	UI_DEV_SETUP   = _IOW('U', 3, sizeofUinputSetup)
+	UI_ABS_SETUP   = _IOW('U', 4, sizeofUinputAbs)
+	UI_SET_EVBIT   = _IOW('U', 100, sizeofInt)
+	UI_SET_KEYBIT  = _IOW('U', 101, sizeofInt)
+	UI_SET_RELBIT  = _IOW('U', 102, sizeofInt)
+	UI_SET_ABSBIT  = _IOW('U', 103, sizeofInt)
+	UI_SET_MSCBIT  = _IOW('U', 104, sizeofInt)
+	UI_SET_LEDBIT  = _IOW('U', 105, sizeofInt)
+	UI_SET_SNDBIT  = _IOW('U', 106, sizeofInt)
+	UI_SET_FFBIT   = _IOW('U', 107, sizeofInt)
+	UI_SET_SWBIT   = _IOW('U', 109, sizeofInt)
+	UI_SET_PROPBIT = _IOW('U', 110, sizeofInt)
+)
+
+func EVIOCGNAME(size int) uintptr {
+	return _IOC(nativeIoctlEncoding.read, 'E', 0x06, uintptr(size))
+}
+
+func EVIOCGPHYS(size int) uintptr {
+	return _IOC(nativeIoctlEncoding.read, 'E', 0x07, uintptr(size))
+}
+
+func EVIOCGUNIQ(size int) uintptr {
+	return _IOC(nativeIoctlEncoding.read, 'E', 0x08, uintptr(size))
+}
+
+func EVIOCGPROP(size int) uintptr {
+	return _IOC(nativeIoctlEncoding.read, 'E', 0x09, uintptr(size))
+}
+
+func EVIOCGKEY(size int) uintptr {
+	return _IOC(nativeIoctlEncoding.read, 'E', 0x18, uintptr(size))
+}
+
+func EVIOCGLED(size int) uintptr {
+	return _IOC(nativeIoctlEncoding.read, 'E', 0x19, uintptr(size))
+}
+
+func EVIOCGSND(size int) uintptr {
+	return _IOC(nativeIoctlEncoding.read, 'E', 0x1a, uintptr(size))
+}
+
+func EVIOCGSW(size int) uintptr {
+	return _IOC(nativeIoctlEncoding.read, 'E', 0x1b, uintptr(size))
+}
+
+func EVIOCGBIT(ev int, size int) uintptr {
+	return _IOC(nativeIoctlEncoding.read, 'E', 0x20+uintptr(ev), uintptr(size))
+}
+
+func EVIOCGABS(abs int) uintptr {
+	return _IOR('E', 0x40+uintptr(abs), sizeofAbsInfo)
+}
+
+func EVIOCSABS(abs int) uintptr {
+	return _IOW('E', 0xc0+uintptr(abs), sizeofAbsInfo)
+}
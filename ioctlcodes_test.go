@@ -0,0 +1,67 @@
+package inputeventsubsystem
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIoctlCodes(t *testing.T) {
+	t.Run("generic encoding", func(t *testing.T) {
+		e := ioctlEncodingGeneric
+
+		// values from linux/input.h on x86_64
+		assert.Equal(t, uintptr(0x80044501), e.ioc(e.read, 'E', 0x01, sizeofInt))
+		assert.Equal(t, uintptr(0x80084502), e.ioc(e.read, 'E', 0x02, sizeofInputID))
+		assert.Equal(t, uintptr(0x80084503), e.ioc(e.read, 'E', 0x03, sizeofRepeat))
+		assert.Equal(t, uintptr(0x40084503), e.ioc(e.write, 'E', 0x03, sizeofRepeat))
+		assert.Equal(t, uintptr(0x80284504), e.ioc(e.read, 'E', 0x04, sizeofKeymapEntry))
+		assert.Equal(t, uintptr(0x40284504), e.ioc(e.write, 'E', 0x04, sizeofKeymapEntry))
+		assert.Equal(t, uintptr(0x40304580), e.ioc(e.write, 'E', 0x80, 48))
+		assert.Equal(t, uintptr(0x402c4580), e.ioc(e.write, 'E', 0x80, 44))
+		assert.Equal(t, uintptr(0x40044590), e.ioc(e.write, 'E', 0x90, sizeofInt))
+		assert.Equal(t, uintptr(0x80104592), e.ioc(e.read, 'E', 0x92, sizeofInputMask))
+		assert.Equal(t, uintptr(0x400445a0), e.ioc(e.write, 'E', 0xa0, sizeofInt))
+		assert.Equal(t, uintptr(0x81004506), e.ioc(e.read, 'E', 0x06, 256))
+		assert.Equal(t, uintptr(0x80604521), e.ioc(e.read, 'E', 0x20+EV_KEY, 96))
+		assert.Equal(t, uintptr(0x80184575), e.ioc(e.read, 'E', 0x40+ABS_MT_POSITION_X, sizeofAbsInfo))
+		assert.Equal(t, uintptr(0x401845f5), e.ioc(e.write, 'E', 0xc0+ABS_MT_POSITION_X, sizeofAbsInfo))
+		assert.Equal(t, uintptr(0x80604518), e.ioc(e.read, 'E', 0x18, 96))
+		assert.Equal(t, uintptr(0x80024519), e.ioc(e.read, 'E', 0x19, 2))
+		assert.Equal(t, uintptr(0x5501), e.ioc(e.none, 'U', 1, 0))
+		assert.Equal(t, uintptr(0x405c5503), e.ioc(e.write, 'U', 3, sizeofUinputSetup))
+		assert.Equal(t, uintptr(0x401c5504), e.ioc(e.write, 'U', 4, sizeofUinputAbs))
+		assert.Equal(t, uintptr(0x40045564), e.ioc(e.write, 'U', 100, sizeofInt))
+	})
+
+	t.Run("powerpc and mips encoding", func(t *testing.T) {
+		e := ioctlEncodingPowerPC
+
+		assert.Equal(t, uintptr(0x40044501), e.ioc(e.read, 'E', 0x01, sizeofInt))
+		assert.Equal(t, uintptr(0x80284504), e.ioc(e.write, 'E', 0x04, sizeofKeymapEntry))
+		assert.Equal(t, uintptr(0x80304580), e.ioc(e.write, 'E', 0x80, 48))
+		assert.Equal(t, uintptr(0x80044590), e.ioc(e.write, 'E', 0x90, sizeofInt))
+		assert.Equal(t, uintptr(0x41004506), e.ioc(e.read, 'E', 0x06, 256))
+		assert.Equal(t, uintptr(0x20004501), e.ioc(e.none, 'E', 0x01, 0))
+	})
+
+	t.Run("native", func(t *testing.T) {
+		if nativeIoctlEncoding != ioctlEncodingGeneric {
+			t.Skip("values checked for the generic encoding")
+		}
+
+		assert.Equal(t, uintptr(0x80044501), EVIOCGVERSION)
+		assert.Equal(t, uintptr(0x40044590), EVIOCGRAB)
+		assert.Equal(t, uintptr(0x81004506), EVIOCGNAME(INPUT_NAME_LEN))
+		assert.Equal(t, uintptr(0x80604521), EVIOCGBIT(EV_KEY, 96))
+		assert.Equal(t, uintptr(0x80184575), EVIOCGABS(ABS_MT_POSITION_X))
+		assert.Equal(t, uintptr(0x401845f5), EVIOCSABS(ABS_MT_POSITION_X))
+
+		if unsafe.Sizeof(uintptr(0)) == 8 {
+			assert.Equal(t, uintptr(0x40304580), EVIOCSFF)
+		} else {
+			assert.Equal(t, uintptr(0x402c4580), EVIOCSFF)
+		}
+	})
+}
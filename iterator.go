@@ -0,0 +1,91 @@
+package inputeventsubsystem
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// ReadInto block until events are available and decode them in the caller buffer, without any allocation.
+// It return the number of events stored in buf
+func (dev *Device) ReadInto(buf []Event) (int, error) {
+	if len(buf) == 0 {
+		return 0, nil
+	}
+
+	raw := unsafe.Slice((*byte)(unsafe.Pointer(&buf[0])), len(buf)*deviceinputeventsize)
+
+	for {
+		if atomic.LoadInt32(&dev.stopped) == 1 {
+			return 0, os.ErrClosed
+		}
+
+		n, err := dev.backend.Read(raw)
+
+		if err == nil {
+			if n%deviceinputeventsize != 0 {
+				return 0, dev.wrapError(OpRead, nil, fmt.Errorf("%w: %d bytes", ErrEventLength, n))
+			}
+
+			if events := dev.filterUnsafeEvents(buf[:n/deviceinputeventsize]); len(events) > 0 {
+				return len(events), nil
+			}
+			continue
+		}
+
+		if err != syscall.EWOULDBLOCK {
+			return 0, dev.readError(err)
+		}
+
+		dev.backend.Wait(time.Second)
+	}
+}
+
+// EventIterator walk the events read from a device in a caller owned buffer.
+// The event returned by Event is valid until the next call to Next
+type EventIterator struct {
+	dev *Device
+	buf []Event
+	n   int
+	pos int
+	err error
+}
+
+func (dev *Device) Iterator(buf []Event) *EventIterator {
+	return &EventIterator{dev: dev, buf: buf, pos: -1}
+}
+
+// Next move to the next event, reading the device when the buffer is exhausted. It return false on error
+func (it *EventIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+
+	it.pos++
+	if it.pos < it.n {
+		return true
+	}
+
+	it.pos = 0
+	it.n, it.err = it.dev.ReadInto(it.buf)
+	return it.err == nil && it.n > 0
+}
+
+func (it *EventIterator) Event() *Event {
+	return &it.buf[it.pos]
+}
+
+// Buffered return the events already read and not walked yet, the current one included
+func (it *EventIterator) Buffered() []Event {
+	if it.pos < 0 || it.pos >= it.n {
+		return nil
+	}
+	return it.buf[it.pos:it.n]
+}
+
+func (it *EventIterator) Err() error {
+	return it.err
+}
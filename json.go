@@ -0,0 +1,309 @@
+package inputeventsubsystem
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrJSONName = errors.New("unknown event type or code name")
+
+// JSONTimeFormat select how the event timestamps are encoded
+type JSONTimeFormat int
+
+const (
+	JSONTimeRFC3339     JSONTimeFormat = iota // string, RFC3339 with the microseconds, UTC
+	JSONTimeNanoseconds                       // number of nanoseconds since the epoch
+)
+
+var (
+	jsonNamesOnce sync.Once
+	jsonTypes     map[string]uint16
+	jsonCodes     map[uint16]map[string]uint16
+)
+
+// reverse tables of the string tables, built on first decode
+func jsonNames() {
+	jsonTypes = make(map[string]uint16)
+	for evtype, name := range evtypeString {
+		jsonTypes[name] = uint16(evtype)
+	}
+
+	jsonCodes = make(map[uint16]map[string]uint16)
+	tables := map[uint16][]map[uint16]string{
+		EV_SYN: {SynCodesString},
+		EV_KEY: {KeyCodesString, BtnCodesString},
+		EV_REL: {RelCodesString},
+		EV_ABS: {AbsCodesString},
+		EV_LED: {LedCodesString},
+		EV_REP: {RepCodesString},
+		EV_FF:  {FFCodesString},
+	}
+	for evtype, typetables := range tables {
+		jsonCodes[evtype] = make(map[string]uint16)
+		for _, table := range typetables {
+			for code, name := range table {
+				jsonCodes[evtype][name] = code
+			}
+		}
+	}
+}
+
+func typeName(evtype uint16) string {
+	if name, ok := evtypeString[int(evtype)]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%x", evtype)
+}
+
+// parseName decode a symbolic name, or the hexadecimal fallback of the unnamed values
+func parseName(name string, names map[string]uint16) (uint16, error) {
+	if v, ok := names[name]; ok {
+		return v, nil
+	}
+
+	if strings.HasPrefix(name, "0x") {
+		if v, err := strconv.ParseUint(name[2:], 16, 16); err == nil {
+			return uint16(v), nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %q", ErrJSONName, name)
+}
+
+type jsonEvent struct {
+	Time  json.RawMessage `json:"time"`
+	Type  string          `json:"type"`
+	Code  string          `json:"code"`
+	Value int32           `json:"value"`
+}
+
+func (ev Event) marshalJSON(format JSONTimeFormat) ([]byte, error) {
+	var t []byte
+
+	if format == JSONTimeNanoseconds {
+		t = strconv.AppendInt(nil, ev.Nanoseconds(), 10)
+	} else {
+		t = strconv.AppendQuote(nil, ev.AsTime().UTC().Format(time.RFC3339Nano))
+	}
+
+	return json.Marshal(jsonEvent{Time: t, Type: typeName(ev.Type), Code: codeName(ev.Type, ev.Code), Value: ev.Value})
+}
+
+// MarshalJSON encode the event with its symbolic type and code names and a RFC3339 timestamp
+func (ev Event) MarshalJSON() ([]byte, error) {
+	return ev.marshalJSON(JSONTimeRFC3339)
+}
+
+// UnmarshalJSON accept both timestamp formats
+func (ev *Event) UnmarshalJSON(data []byte) error {
+	var j jsonEvent
+
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	jsonNamesOnce.Do(jsonNames)
+
+	evtype, err := parseName(j.Type, jsonTypes)
+	if err != nil {
+		return err
+	}
+	code, err := parseName(j.Code, jsonCodes[evtype])
+	if err != nil {
+		return err
+	}
+
+	var ns int64
+	if bytes.HasPrefix(j.Time, []byte(`"`)) {
+		var s string
+		if err := json.Unmarshal(j.Time, &s); err != nil {
+			return err
+		}
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return err
+		}
+		ns = t.UnixNano()
+	} else if err := json.Unmarshal(j.Time, &ns); err != nil {
+		return err
+	}
+
+	*ev = Event{Type: evtype, Code: code, Value: j.Value}
+	setTimeval(&ev.Time, ns/1000000000, (ns%1000000000)/1000)
+	return nil
+}
+
+type jsonAbsInfo struct {
+	Value      int32 `json:"value"`
+	Minimum    int32 `json:"minimum"`
+	Maximum    int32 `json:"maximum"`
+	Fuzz       int32 `json:"fuzz"`
+	Flat       int32 `json:"flat"`
+	Resolution int32 `json:"resolution"`
+}
+
+type jsonDevice struct {
+	Name         string                 `json:"name"`
+	Bus          uint16                 `json:"bus"`
+	VendorID     uint16                 `json:"vendor"`
+	ProductID    uint16                 `json:"product"`
+	Version      uint16                 `json:"version"`
+	Phys         string                 `json:"phys,omitempty"`
+	Uniq         string                 `json:"uniq,omitempty"`
+	Properties   []int                  `json:"properties"`
+	Capabilities map[string][]string    `json:"capabilities"`
+	Absinfos     map[string]jsonAbsInfo `json:"absinfo"`
+}
+
+func (d *EvemuDevice) toJSON() jsonDevice {
+	j := jsonDevice{
+		Name:         d.Name,
+		Bus:          d.Bus,
+		VendorID:     d.VendorID,
+		ProductID:    d.ProductID,
+		Version:      d.Version,
+		Properties:   append([]int{}, d.Properties...),
+		Capabilities: make(map[string][]string),
+		Absinfos:     make(map[string]jsonAbsInfo),
+	}
+
+	for evtype, codes := range d.Codes {
+		sorted := append([]int(nil), codes...)
+		sort.Ints(sorted)
+
+		names := make([]string, 0, len(sorted))
+		for _, code := range sorted {
+			names = append(names, codeName(uint16(evtype), uint16(code)))
+		}
+		j.Capabilities[typeName(uint16(evtype))] = names
+	}
+
+	for code, a := range d.Absinfos {
+		j.Absinfos[codeName(EV_ABS, uint16(code))] = jsonAbsInfo(a)
+	}
+	return j
+}
+
+func (j *jsonDevice) toDescription() (EvemuDevice, error) {
+	jsonNamesOnce.Do(jsonNames)
+
+	d := EvemuDevice{
+		Name:       j.Name,
+		Bus:        j.Bus,
+		VendorID:   j.VendorID,
+		ProductID:  j.ProductID,
+		Version:    j.Version,
+		Properties: append([]int{}, j.Properties...),
+		Codes:      make(map[int][]int),
+		Absinfos:   make(map[int]AbsInfo),
+	}
+
+	for typename, names := range j.Capabilities {
+		evtype, err := parseName(typename, jsonTypes)
+		if err != nil {
+			return d, err
+		}
+
+		codes := make([]int, 0, len(names))
+		for _, name := range names {
+			code, err := parseName(name, jsonCodes[evtype])
+			if err != nil {
+				return d, err
+			}
+			codes = append(codes, int(code))
+		}
+		sort.Ints(codes)
+		d.Codes[int(evtype)] = codes
+	}
+
+	for name, a := range j.Absinfos {
+		code, err := parseName(name, jsonCodes[EV_ABS])
+		if err != nil {
+			return d, err
+		}
+		d.Absinfos[int(code)] = AbsInfo(a)
+	}
+
+	return d, nil
+}
+
+func (d EvemuDevice) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.toJSON())
+}
+
+func (d *EvemuDevice) UnmarshalJSON(data []byte) error {
+	var j jsonDevice
+
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	desc, err := j.toDescription()
+	if err != nil {
+		return err
+	}
+	*d = desc
+	return nil
+}
+
+// MarshalJSON describe the device: metadata, capabilities and absinfos
+func (dev *Device) MarshalJSON() ([]byte, error) {
+	desc, err := dev.EvemuDevice()
+	if err != nil {
+		return nil, err
+	}
+
+	j := desc.toJSON()
+	j.Phys = dev.Phy
+	j.Uniq = dev.Uniq
+	return json.Marshal(j)
+}
+
+// JSONLinesEncoder write one JSON event per line
+type JSONLinesEncoder struct {
+	w      io.Writer
+	format JSONTimeFormat
+}
+
+func NewJSONLinesEncoder(w io.Writer, format JSONTimeFormat) *JSONLinesEncoder {
+	return &JSONLinesEncoder{w: w, format: format}
+}
+
+func (e *JSONLinesEncoder) Encode(events ...Event) error {
+	var buf []byte
+
+	for _, ev := range events {
+		line, err := ev.marshalJSON(e.format)
+		if err != nil {
+			return err
+		}
+		buf = append(append(buf, line...), '\n')
+	}
+
+	_, err := e.w.Write(buf)
+	return err
+}
+
+// JSONLinesDecoder read the events written by JSONLinesEncoder
+type JSONLinesDecoder struct {
+	dec *json.Decoder
+}
+
+func NewJSONLinesDecoder(r io.Reader) *JSONLinesDecoder {
+	return &JSONLinesDecoder{dec: json.NewDecoder(r)}
+}
+
+// Next return the next event, io.EOF at the end of the stream
+func (d *JSONLinesDecoder) Next() (Event, error) {
+	var ev Event
+
+	err := d.dec.Decode(&ev)
+	return ev, err
+}
@@ -0,0 +1,104 @@
+package inputeventsubsystem
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventJSON(t *testing.T) {
+	var ev Event
+	setTimeval(&ev.Time, 1700000000, 500001)
+	ev.Type, ev.Code, ev.Value = EV_KEY, BTN_SOUTH, 1
+
+	data, err := json.Marshal(ev)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"time":"2023-11-14T22:13:20.500001Z","type":"EV_KEY","code":"BTN_A","value":1}`, string(data))
+
+	var ev2 Event
+	assert.Nil(t, json.Unmarshal(data, &ev2))
+	assert.Equal(t, ev, ev2)
+
+	data, err = ev.marshalJSON(JSONTimeNanoseconds)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"time":1700000000500001000,"type":"EV_KEY","code":"BTN_A","value":1}`, string(data))
+
+	assert.Nil(t, json.Unmarshal(data, &ev2))
+	assert.Equal(t, ev, ev2)
+
+	// unnamed values fall back to hexadecimal
+	ev.Type, ev.Code = EV_PWR, 0x42
+	data, _ = json.Marshal(ev)
+	assert.Contains(t, string(data), `"type":"0x16","code":"0x42"`)
+	assert.Nil(t, json.Unmarshal(data, &ev2))
+	assert.Equal(t, ev, ev2)
+
+	err = json.Unmarshal([]byte(`{"time":0,"type":"EV_KEY","code":"BTN_NOPE","value":1}`), &ev2)
+	assert.True(t, errors.Is(err, ErrJSONName))
+}
+
+func TestEventJSONNames(t *testing.T) {
+	tables := map[uint16][]map[uint16]string{
+		EV_SYN: {SynCodesString},
+		EV_KEY: {KeyCodesString, BtnCodesString},
+		EV_REL: {RelCodesString},
+		EV_ABS: {AbsCodesString},
+		EV_LED: {LedCodesString},
+		EV_FF:  {FFCodesString},
+	}
+
+	for evtype, typetables := range tables {
+		for _, table := range typetables {
+			for code := range table {
+				data, err := json.Marshal(Event{Type: evtype, Code: code})
+				assert.Nil(t, err)
+
+				var ev Event
+				assert.Nil(t, json.Unmarshal(data, &ev))
+				assert.Equal(t, code, ev.Code, string(data))
+			}
+		}
+	}
+}
+
+func TestDeviceDescriptionJSON(t *testing.T) {
+	desc, _, err := ParseEvemu(strings.NewReader(evemuSample))
+	assert.Nil(t, err)
+
+	data, err := json.Marshal(desc)
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), `"EV_ABS":["ABS_X","ABS_Y","ABS_Z","ABS_RX","ABS_RY","ABS_RZ","ABS_HAT0X","ABS_HAT0Y"]`)
+	assert.Contains(t, string(data), `"ABS_HAT0X":{"value":0,"minimum":-1,"maximum":1,"fuzz":0,"flat":0,"resolution":0}`)
+
+	var desc2 EvemuDevice
+	assert.Nil(t, json.Unmarshal(data, &desc2))
+	assert.Equal(t, desc, desc2)
+}
+
+func TestJSONLines(t *testing.T) {
+	_, events, err := ParseEvemu(strings.NewReader(evemuSample))
+	assert.Nil(t, err)
+
+	for _, format := range []JSONTimeFormat{JSONTimeRFC3339, JSONTimeNanoseconds} {
+		var buf bytes.Buffer
+		assert.Nil(t, NewJSONLinesEncoder(&buf, format).Encode(events...))
+		assert.Equal(t, len(events), strings.Count(buf.String(), "\n"))
+
+		dec := NewJSONLinesDecoder(&buf)
+		var decoded []Event
+		for {
+			ev, err := dec.Next()
+			if err == io.EOF {
+				break
+			}
+			assert.Nil(t, err)
+			decoded = append(decoded, ev)
+		}
+		assert.Equal(t, events, decoded)
+	}
+}
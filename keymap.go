@@ -0,0 +1,134 @@
+package inputeventsubsystem
+
+import (
+	"encoding/binary"
+	"errors"
+	"syscall"
+)
+
+const (
+	INPUT_KEYMAP_BY_INDEX = 1 << 0
+	KEYMAP_SCANCODE_LEN   = 32
+)
+
+var ErrScancodeLength = errors.New("invalid scancode length")
+
+// KeymapEntry mirror struct input_keymap_entry, the scancode is variable length (1 to 32 bytes)
+type KeymapEntry struct {
+	Index    uint16
+	Keycode  uint32
+	Scancode []byte
+}
+
+// ScancodeFromUint32 encode a scalar scancode the way drivers expect it (4 bytes)
+func ScancodeFromUint32(scancode uint32) []byte {
+	var data []byte = make([]byte, 4)
+	binary.NativeEndian.PutUint32(data, scancode)
+	return data
+}
+
+// ScancodeUint32 return the scalar value of 1, 2 or 4 bytes scancode
+func (k *KeymapEntry) ScancodeUint32() (uint32, error) {
+	switch len(k.Scancode) {
+	case 1:
+		return uint32(k.Scancode[0]), nil
+	case 2:
+		return uint32(binary.NativeEndian.Uint16(k.Scancode)), nil
+	case 4:
+		return binary.NativeEndian.Uint32(k.Scancode), nil
+	}
+	return 0, ErrScancodeLength
+}
+
+func (k *KeymapEntry) Pack(flags uint8) ([]byte, error) {
+	if len(k.Scancode) > KEYMAP_SCANCODE_LEN {
+		return nil, ErrScancodeLength
+	}
+
+	var data []byte = make([]byte, KEYMAP_SIZE)
+
+	data[0] = flags
+	data[1] = uint8(len(k.Scancode))
+	binary.NativeEndian.PutUint16(data[2:], k.Index)
+	binary.NativeEndian.PutUint32(data[4:], k.Keycode)
+	copy(data[8:], k.Scancode)
+	return data, nil
+}
+
+func (k *KeymapEntry) Unpack(data []byte) {
+	length := int(data[1])
+	if length > KEYMAP_SCANCODE_LEN {
+		length = KEYMAP_SCANCODE_LEN
+	}
+
+	k.Index = binary.NativeEndian.Uint16(data[2:])
+	k.Keycode = binary.NativeEndian.Uint32(data[4:])
+	k.Scancode = make([]byte, length)
+	copy(k.Scancode, data[8:8+length])
+}
+
+func (dev *Device) getKeymap(entry KeymapEntry, flags uint8) (KeymapEntry, error) {
+	data, err := entry.Pack(flags)
+	if err != nil {
+		return entry, err
+	}
+
+	if err = dev.backend.Keycode(data); err != nil {
+		return entry, dev.wrapError(OpIoctl, nil, err)
+	}
+
+	entry.Unpack(data)
+	return entry, nil
+}
+
+// KeymapByIndex return the index-th entry of the device keymap
+func (dev *Device) KeymapByIndex(index uint16) (KeymapEntry, error) {
+	return dev.getKeymap(KeymapEntry{Index: index}, INPUT_KEYMAP_BY_INDEX)
+}
+
+// KeymapByScancode return the keymap entry of the scancode
+func (dev *Device) KeymapByScancode(scancode []byte) (KeymapEntry, error) {
+	return dev.getKeymap(KeymapEntry{Scancode: scancode}, 0)
+}
+
+// Keymap iterate the device keymap by index until the end of the table
+func (dev *Device) Keymap() ([]KeymapEntry, error) {
+	var entries []KeymapEntry
+
+	for index := 0; index <= 0xffff; index++ {
+		entry, err := dev.KeymapByIndex(uint16(index))
+		if errors.Is(err, syscall.EINVAL) {
+			break
+		}
+		if err != nil {
+			return entries, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// SetKeymap write the entry by scancode, or by index if byIndex is set
+func (dev *Device) SetKeymap(entry KeymapEntry, byIndex bool) error {
+	var flags uint8
+	if byIndex {
+		flags = INPUT_KEYMAP_BY_INDEX
+	}
+
+	data, err := entry.Pack(flags)
+	if err != nil {
+		return err
+	}
+	return dev.wrapError(OpIoctl, nil, dev.backend.SetKeycode(data))
+}
+
+// RemapKey map the scancode to the keycode, falling back to the legacy EVIOCSKEYCODE on old kernels and drivers,
+// evdev answer EINVAL to the unsupported ioctls
+func (dev *Device) RemapKey(scancode uint32, keycode uint32) error {
+	err := dev.SetKeymap(KeymapEntry{Keycode: keycode, Scancode: ScancodeFromUint32(scancode)}, false)
+	if errors.Is(err, syscall.ENOTTY) || errors.Is(err, syscall.EINVAL) {
+		return dev.wrapError(OpIoctl, nil, dev.backend.SetLegacyKeycode(scancode, keycode))
+	}
+	return err
+}
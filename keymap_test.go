@@ -0,0 +1,58 @@
+package inputeventsubsystem
+
+import (
+	"encoding/binary"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeymapEntry(t *testing.T) {
+	k := KeymapEntry{Index: 7, Keycode: KEY_A, Scancode: ScancodeFromUint32(0x70004)}
+
+	data, err := k.Pack(INPUT_KEYMAP_BY_INDEX)
+	assert.Nil(t, err)
+	assert.Len(t, data, KEYMAP_SIZE)
+	assert.Equal(t, []byte{0x01, 0x04}, data[:2])
+	assert.Equal(t, uint16(7), binary.NativeEndian.Uint16(data[2:]))
+	assert.Equal(t, uint32(KEY_A), binary.NativeEndian.Uint32(data[4:]))
+	assert.Equal(t, uint32(0x70004), binary.NativeEndian.Uint32(data[8:]))
+
+	var k2 KeymapEntry
+	k2.Unpack(data)
+	assert.Equal(t, k, k2)
+
+	v, err := k2.ScancodeUint32()
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(0x70004), v)
+
+	k3 := KeymapEntry{Scancode: []byte{1, 2, 3}}
+	_, err = k3.ScancodeUint32()
+	assert.Equal(t, ErrScancodeLength, err)
+
+	k4 := KeymapEntry{Scancode: make([]byte, KEYMAP_SCANCODE_LEN+1)}
+	_, err = k4.Pack(0)
+	assert.Equal(t, ErrScancodeLength, err)
+}
+
+// legacyKeymapDevice is a driver without EVIOCSKEYCODE_V2, evdev answer EINVAL
+type legacyKeymapDevice struct {
+	*FakeDevice
+}
+
+func (legacyKeymapDevice) SetKeycode(entry []byte) error {
+	return syscall.EINVAL
+}
+
+func TestRemapKeyLegacy(t *testing.T) {
+	dev, err := OpenBackend("fake", legacyKeymapDevice{fakeGamepad()})
+	assert.Nil(t, err)
+	defer dev.Close()
+
+	assert.Nil(t, dev.RemapKey(0x70004, KEY_B))
+
+	entry, err := dev.KeymapByScancode(ScancodeFromUint32(0x70004))
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(KEY_B), entry.Keycode)
+}
@@ -0,0 +1,72 @@
+package inputeventsubsystem
+
+import "encoding/binary"
+
+// EventLayout describe the binary layout of struct input_event
+type EventLayout struct {
+	Size        int              // size of struct input_event
+	WordSize    int              // size of the sec and usec fields
+	UnsignedSec bool             // sec is an unsigned long instead of a time_t
+	Order       binary.ByteOrder // nil means the native byte order
+}
+
+var (
+	// 64-bit userspace, struct timeval with a 64-bit time_t
+	EventLayoutTimeval64 = EventLayout{Size: 24, WordSize: 8}
+	// legacy 32-bit userspace, struct timeval with a 32-bit time_t
+	EventLayoutTimeval32 = EventLayout{Size: 16, WordSize: 4}
+	// 32-bit userspace built with __USE_TIME_BITS64, __sec and __usec are separate unsigned longs
+	EventLayoutTime64 = EventLayout{Size: 16, WordSize: 4, UnsignedSec: true}
+)
+
+// WithOrder return the same layout with another byte order, mostly to decode captures of foreign architectures
+func (l EventLayout) WithOrder(order binary.ByteOrder) EventLayout {
+	l.Order = order
+	return l
+}
+
+func (l EventLayout) order() binary.ByteOrder {
+	if l.Order == nil {
+		return binary.NativeEndian
+	}
+	return l.Order
+}
+
+func (l EventLayout) word(data []byte) int64 {
+	if l.WordSize == 8 {
+		return int64(l.order().Uint64(data))
+	}
+	return int64(int32(l.order().Uint32(data)))
+}
+
+func (l EventLayout) putWord(data []byte, v int64) {
+	if l.WordSize == 8 {
+		l.order().PutUint64(data, uint64(v))
+	} else {
+		l.order().PutUint32(data, uint32(v))
+	}
+}
+
+// Decode the input_event at the beginning of data
+func (l EventLayout) Decode(data []byte) (sec int64, usec int64, evtype uint16, code uint16, value int32) {
+	sec = l.word(data)
+	if l.UnsignedSec && l.WordSize == 4 {
+		sec = int64(uint32(sec))
+	}
+	usec = l.word(data[l.WordSize:])
+
+	evtype = l.order().Uint16(data[2*l.WordSize:])
+	code = l.order().Uint16(data[2*l.WordSize+2:])
+	value = int32(l.order().Uint32(data[2*l.WordSize+4:]))
+	return
+}
+
+// Encode an input_event at the beginning of data
+func (l EventLayout) Encode(data []byte, sec int64, usec int64, evtype uint16, code uint16, value int32) {
+	l.putWord(data, sec)
+	l.putWord(data[l.WordSize:], usec)
+
+	l.order().PutUint16(data[2*l.WordSize:], evtype)
+	l.order().PutUint16(data[2*l.WordSize+2:], code)
+	l.order().PutUint32(data[2*l.WordSize+4:], uint32(value))
+}
@@ -0,0 +1,144 @@
+package inputeventsubsystem
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventLayout(t *testing.T) {
+	t.Run("timeval 64", func(t *testing.T) {
+		sample := []byte{
+			0x00, 0x00, 0x00, 0x80, 0x00, 0x00, 0x00, 0x00, // sec 2^31, after 2038
+			0x61, 0xcf, 0x0b, 0x00, 0x00, 0x00, 0x00, 0x00, // usec
+			0x01, 0x00, 0x2e, 0x00, 0x01, 0x00, 0x00, 0x00,
+		}
+		layout := EventLayoutTimeval64.WithOrder(binary.LittleEndian)
+
+		sec, usec, evtype, code, value := layout.Decode(sample)
+		assert.Equal(t, int64(0x80000000), sec)
+		assert.Equal(t, int64(0xbcf61), usec)
+		assert.Equal(t, uint16(EV_KEY), evtype)
+		assert.Equal(t, uint16(KEY_C), code)
+		assert.Equal(t, int32(1), value)
+
+		out := make([]byte, layout.Size)
+		layout.Encode(out, sec, usec, evtype, code, value)
+		assert.Equal(t, sample, out)
+	})
+
+	t.Run("time64 32-bit", func(t *testing.T) {
+		sample := []byte{
+			0x00, 0x00, 0x00, 0x80, // __sec 2^31, after 2038
+			0x61, 0xcf, 0x0b, 0x00, // __usec
+			0x03, 0x00, 0x35, 0x00, 0xff, 0xff, 0xff, 0xff,
+		}
+		layout := EventLayoutTime64.WithOrder(binary.LittleEndian)
+
+		sec, usec, evtype, code, value := layout.Decode(sample)
+		assert.Equal(t, int64(0x80000000), sec)
+		assert.Equal(t, int64(0xbcf61), usec)
+		assert.Equal(t, uint16(EV_ABS), evtype)
+		assert.Equal(t, uint16(ABS_MT_POSITION_X), code)
+		assert.Equal(t, int32(-1), value)
+
+		out := make([]byte, layout.Size)
+		layout.Encode(out, sec, usec, evtype, code, value)
+		assert.Equal(t, sample, out)
+
+		// the legacy timeval layout wrap at 2038
+		sec, _, _, _, _ = EventLayoutTimeval32.WithOrder(binary.LittleEndian).Decode(sample)
+		assert.Equal(t, int64(-0x80000000), sec)
+	})
+
+	t.Run("s390x big endian", func(t *testing.T) {
+		sample := []byte{
+			0x00, 0x00, 0x00, 0x00, 0x65, 0x93, 0xaf, 0x53,
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x0b, 0xcf, 0x61,
+			0x00, 0x01, 0x00, 0x2e, 0x00, 0x00, 0x00, 0x01,
+		}
+
+		sec, usec, evtype, code, value := EventLayoutTimeval64.WithOrder(binary.BigEndian).Decode(sample)
+		assert.Equal(t, int64(0x6593af53), sec)
+		assert.Equal(t, int64(0xbcf61), usec)
+		assert.Equal(t, uint16(EV_KEY), evtype)
+		assert.Equal(t, uint16(KEY_C), code)
+		assert.Equal(t, int32(1), value)
+	})
+
+	t.Run("native", func(t *testing.T) {
+		assert.Equal(t, deviceinputeventsize, NativeEventLayout.Size)
+		assert.Nil(t, NativeEventLayout.Order)
+	})
+}
+
+func TestEventLayoutMatrix(t *testing.T) {
+	tests := []struct {
+		name   string
+		layout EventLayout
+	}{
+		{"64-bit little endian", EventLayoutTimeval64.WithOrder(binary.LittleEndian)},
+		{"64-bit big endian", EventLayoutTimeval64.WithOrder(binary.BigEndian)},
+		{"32-bit little endian", EventLayoutTime64.WithOrder(binary.LittleEndian)},
+		{"32-bit big endian", EventLayoutTime64.WithOrder(binary.BigEndian)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := tt.layout.Order
+			w := tt.layout.WordSize
+			sample := make([]byte, tt.layout.Size)
+
+			if w == 8 {
+				order.PutUint64(sample[0:], 1704177491)
+				order.PutUint64(sample[8:], 773985)
+			} else {
+				order.PutUint32(sample[0:], 1704177491)
+				order.PutUint32(sample[4:], 773985)
+			}
+			order.PutUint16(sample[2*w:], EV_REL)
+			order.PutUint16(sample[2*w+2:], REL_WHEEL)
+			order.PutUint32(sample[2*w+4:], uint32(0xffffffff))
+
+			sec, usec, evtype, code, value := tt.layout.Decode(sample)
+			assert.Equal(t, int64(1704177491), sec)
+			assert.Equal(t, int64(773985), usec)
+			assert.Equal(t, uint16(EV_REL), evtype)
+			assert.Equal(t, uint16(REL_WHEEL), code)
+			assert.Equal(t, int32(-1), value)
+
+			out := make([]byte, tt.layout.Size)
+			tt.layout.Encode(out, sec, usec, evtype, code, value)
+			assert.Equal(t, sample, out)
+		})
+	}
+}
+
+func TestAbsInfoOrder(t *testing.T) {
+	a := AbsInfo{Value: 1, Minimum: -4096, Maximum: 4095, Fuzz: 2, Flat: 3, Resolution: 40}
+
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		data := a.PackOrder(order)
+		assert.Equal(t, uint32(0xfffff000), order.Uint32(data[4:]))
+
+		var a2 AbsInfo
+		assert.Nil(t, a2.UnpackOrder(data, order))
+		assert.Equal(t, a, a2)
+	}
+
+	var a3 AbsInfo
+	assert.Nil(t, a3.UnpackOrder([]byte{0, 0, 0, 1, 0, 0, 0, 2, 0, 0, 0, 3, 0, 0, 0, 4, 0, 0, 0, 5, 0, 0, 0, 6}, binary.BigEndian))
+	assert.Equal(t, AbsInfo{Value: 1, Minimum: 2, Maximum: 3, Fuzz: 4, Flat: 5, Resolution: 6}, a3)
+}
+
+func TestEventTime(t *testing.T) {
+	events, err := UnpackDeviceInputEvents(data)
+	assert.Nil(t, err)
+	ev := events[0]
+
+	assert.Equal(t, int64(1704177491773985000), ev.Nanoseconds())
+	assert.Equal(t, ev.Time.Nano(), ev.Nanoseconds())
+	assert.Equal(t, int64(1704177491), ev.AsTime().Unix())
+	assert.Equal(t, 773985000, ev.AsTime().Nanosecond())
+}
@@ -0,0 +1,169 @@
+package inputeventsubsystem
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	SysfsLedsPath  = "/sys/class/leds"
+	SysfsInputPath = "/sys/class/input"
+)
+
+var ErrLedNotFound = errors.New("led not found")
+
+// SetLED switch on or off a led through an EV_LED write
+func (dev *Device) SetLED(code int, on bool) error {
+	var value int32
+	if on {
+		value = 1
+	}
+
+	if err := dev.WriteEvent(EV_LED, uint16(code), value); err != nil {
+		return err
+	}
+	return dev.WriteEvent(EV_SYN, SYN_REPORT, 0)
+}
+
+// LEDs return the state of each led supported by the device and of each lit led
+func (dev *Device) LEDs() (map[int]bool, error) {
+
+	if err := dev.ensureCapabilities(); err != nil {
+		return nil, err
+	}
+
+	ledsbits, err := dev.LesdsState()
+	if err != nil {
+		return nil, err
+	}
+
+	leds := make(map[int]bool)
+
+	for ledcode := range dev.Capabilities[EV_LED] {
+		leds[ledcode] = false
+	}
+
+	for ledcode := 0; ledcode <= LED_MAX; ledcode++ {
+		if ledsbits[ledcode/8]&(1<<uint(ledcode%8)) != 0 {
+			leds[ledcode] = true
+		}
+	}
+
+	return leds, nil
+}
+
+// SysfsLED is a led driven through the sysfs led class (/sys/class/leds/<name>)
+type SysfsLED struct {
+	Name string
+	Path string
+}
+
+func ScanSysfsLEDs(ledspath string) []SysfsLED {
+	var leds []SysfsLED
+
+	if files, err := os.ReadDir(ledspath); err == nil {
+		for _, file := range files {
+			pathled := filepath.Join(ledspath, file.Name())
+			if _, err := os.Stat(filepath.Join(pathled, "brightness")); err == nil {
+				leds = append(leds, SysfsLED{Name: file.Name(), Path: pathled})
+			}
+		}
+	}
+
+	return leds
+}
+
+func OpenSysfsLED(ledspath string, name string) (*SysfsLED, error) {
+	pathled := filepath.Join(ledspath, name)
+
+	if _, err := os.Stat(filepath.Join(pathled, "brightness")); err != nil {
+		return nil, ErrLedNotFound
+	}
+
+	return &SysfsLED{Name: name, Path: pathled}, nil
+}
+
+func (l *SysfsLED) readInt(attr string) (int, error) {
+	data, err := os.ReadFile(filepath.Join(l.Path, attr))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(data)))
+}
+
+func (l *SysfsLED) Brightness() (int, error) {
+	return l.readInt("brightness")
+}
+
+func (l *SysfsLED) MaxBrightness() (int, error) {
+	return l.readInt("max_brightness")
+}
+
+func (l *SysfsLED) SetBrightness(value int) error {
+	return os.WriteFile(filepath.Join(l.Path, "brightness"), []byte(strconv.Itoa(value)), 0644)
+}
+
+// Set switch the led on at its max brightness or off
+func (l *SysfsLED) Set(on bool) error {
+	var value int
+
+	if on {
+		var err error
+		if value, err = l.MaxBrightness(); err != nil {
+			return err
+		}
+	}
+
+	return l.SetBrightness(value)
+}
+
+// Code return the EV_LED code matching the led function (input3::capslock => LED_CAPSL)
+func (l *SysfsLED) Code() (int, bool) {
+	parts := strings.Split(l.Name, ":")
+	function := parts[len(parts)-1]
+
+	for code, name := range sysfsLedFunctions {
+		if name == function {
+			return int(code), true
+		}
+	}
+	return 0, false
+}
+
+// function names given by the kernel input-leds driver
+var sysfsLedFunctions = map[uint16]string{
+	LED_NUML:     "numlock",
+	LED_CAPSL:    "capslock",
+	LED_SCROLLL:  "scrolllock",
+	LED_COMPOSE:  "compose",
+	LED_KANA:     "kana",
+	LED_SLEEP:    "sleep",
+	LED_SUSPEND:  "suspend",
+	LED_MUTE:     "mute",
+	LED_MISC:     "misc",
+	LED_MAIL:     "mail",
+	LED_CHARGING: "charging",
+}
+
+// SysfsLEDs return the sysfs leds owned by the input device (named inputN::function)
+func (dev *Device) SysfsLEDs(ledspath string) ([]SysfsLED, error) {
+
+	inputdir, err := filepath.EvalSymlinks(filepath.Join(SysfsInputPath, filepath.Base(dev.Fn), "device"))
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := filepath.Base(inputdir) + "::"
+
+	var leds []SysfsLED
+	for _, led := range ScanSysfsLEDs(ledspath) {
+		if strings.HasPrefix(led.Name, prefix) {
+			leds = append(leds, led)
+		}
+	}
+
+	return leds, nil
+}
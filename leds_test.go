@@ -0,0 +1,63 @@
+package inputeventsubsystem
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSysfsLED(t *testing.T) {
+	ledspath := t.TempDir()
+
+	for _, name := range []string{"input3::capslock", "input3::numlock", "platform::wlan"} {
+		assert.Nil(t, os.MkdirAll(filepath.Join(ledspath, name), 0755))
+		assert.Nil(t, os.WriteFile(filepath.Join(ledspath, name, "brightness"), []byte("0\n"), 0644))
+		assert.Nil(t, os.WriteFile(filepath.Join(ledspath, name, "max_brightness"), []byte("1\n"), 0644))
+	}
+	assert.Nil(t, os.MkdirAll(filepath.Join(ledspath, "notaled"), 0755))
+
+	assert.Len(t, ScanSysfsLEDs(ledspath), 3)
+
+	_, err := OpenSysfsLED(ledspath, "notaled")
+	assert.Equal(t, ErrLedNotFound, err)
+
+	led, err := OpenSysfsLED(ledspath, "input3::capslock")
+	assert.Nil(t, err)
+
+	code, ok := led.Code()
+	assert.True(t, ok)
+	assert.Equal(t, LED_CAPSL, code)
+
+	assert.Nil(t, led.Set(true))
+	v, err := led.Brightness()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v)
+
+	assert.Nil(t, led.Set(false))
+	v, _ = led.Brightness()
+	assert.Equal(t, 0, v)
+
+	_, ok = (&SysfsLED{Name: "platform::wlan"}).Code()
+	assert.False(t, ok)
+}
+
+func TestLEDs(t *testing.T) {
+	fake := NewFakeDevice(EvemuDevice{
+		Name:  "keyboard",
+		Codes: map[int][]int{EV_SYN: {SYN_REPORT}, EV_LED: {LED_NUML, LED_MAX}},
+	})
+
+	dev, err := OpenBackend("fake", fake)
+	assert.Nil(t, err)
+	defer dev.Close()
+
+	// the probe and the led state agree on the last code
+	assert.Contains(t, dev.Capabilities[EV_LED], LED_MAX)
+	assert.Nil(t, dev.SetLED(LED_MAX, true))
+
+	leds, err := dev.LEDs()
+	assert.Nil(t, err)
+	assert.Equal(t, map[int]bool{LED_NUML: false, LED_MAX: true}, leds)
+}
@@ -0,0 +1,120 @@
+package inputeventsubsystem
+
+import "golang.org/x/sys/unix"
+
+// Opener open a device node and return its file descriptor, it can be replaced by tests
+type Opener func(path string, flags int) (int, error)
+
+func unixOpener(path string, flags int) (int, error) {
+	return unix.Open(path, flags, 0)
+}
+
+type openConfig struct {
+	buffersize int
+	batchsize  int
+	policy     OverflowPolicy
+	readonly   bool
+	lazyprobe  bool
+	grab       bool
+	clockid    int
+	setclock   bool
+	eventmasks map[int][]int
+	opener     Opener
+}
+
+func defaultOpenConfig() openConfig {
+	return openConfig{
+		buffersize: 1,
+		batchsize:  defaultReadBatchSize,
+		eventmasks: make(map[int][]int),
+		opener:     unixOpener,
+	}
+}
+
+// apply the settings that need an opened device
+func (c *openConfig) apply(dev *Device) error {
+
+	if c.setclock {
+		if err := dev.SetClock(c.clockid); err != nil {
+			return err
+		}
+	}
+
+	for evtype, codes := range c.eventmasks {
+		if err := dev.SetEventMask(evtype, codes); err != nil {
+			return err
+		}
+	}
+
+	if c.grab {
+		if err := dev.Grab(true); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type OpenOption func(*openConfig)
+
+// WithBufferSize set the number of batches queued on the read channels
+func WithBufferSize(size int) OpenOption {
+	return func(c *openConfig) {
+		c.buffersize = size
+	}
+}
+
+// WithReadBatchSize set the maximum number of events read at once
+func WithReadBatchSize(size int) OpenOption {
+	return func(c *openConfig) {
+		c.batchsize = size
+	}
+}
+
+func WithOverflowPolicy(policy OverflowPolicy) OpenOption {
+	return func(c *openConfig) {
+		c.policy = policy
+	}
+}
+
+// WithReadOnly open the node O_RDONLY, writes (leds, force feedback...) will fail
+func WithReadOnly() OpenOption {
+	return func(c *openConfig) {
+		c.readonly = true
+	}
+}
+
+// WithLazyProbe skip the capabilities probing, they are read by ProbeCapabilities or on first need
+func WithLazyProbe() OpenOption {
+	return func(c *openConfig) {
+		c.lazyprobe = true
+	}
+}
+
+// WithGrab grab the device once opened
+func WithGrab() OpenOption {
+	return func(c *openConfig) {
+		c.grab = true
+	}
+}
+
+// WithClock select the clock used to timestamp the events
+func WithClock(clockid int) OpenOption {
+	return func(c *openConfig) {
+		c.clockid = clockid
+		c.setclock = true
+	}
+}
+
+// WithEventMask deliver only the codes of evtype, it can be given once per event type
+func WithEventMask(evtype int, codes []int) OpenOption {
+	return func(c *openConfig) {
+		c.eventmasks[evtype] = codes
+	}
+}
+
+func WithOpener(opener Opener) OpenOption {
+	return func(c *openConfig) {
+		c.opener = opener
+	}
+}
@@ -0,0 +1,65 @@
+package inputeventsubsystem
+
+import (
+	"errors"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+func TestOpenWithOptions(t *testing.T) {
+	t.Run("opener error", func(t *testing.T) {
+		errOpen := errors.New("open failed")
+		var gotpath string
+		var gotflags int
+
+		_, err := OpenWithOptions("/dev/input/event42", WithReadOnly(), WithOpener(func(path string, flags int) (int, error) {
+			gotpath, gotflags = path, flags
+			return -1, errOpen
+		}))
+
+		assert.ErrorIs(t, err, errOpen)
+		assert.Equal(t, "/dev/input/event42", gotpath)
+		assert.Equal(t, syscall.O_RDONLY, gotflags&syscall.O_ACCMODE)
+		assert.NotZero(t, gotflags&syscall.O_NONBLOCK)
+	})
+
+	t.Run("not an evdev node", func(t *testing.T) {
+		var p [2]int
+		assert.Nil(t, unix.Pipe2(p[:], unix.O_CLOEXEC))
+		defer unix.Close(p[1])
+
+		var gotflags int
+		_, err := OpenWithOptions("pipe", WithOpener(func(path string, flags int) (int, error) {
+			gotflags = flags
+			return p[0], nil
+		}))
+
+		assert.ErrorIs(t, err, ErrDriverVersion)
+		assert.ErrorIs(t, err, ErrNotEvdev)
+		assert.Equal(t, syscall.O_RDWR, gotflags&syscall.O_ACCMODE)
+
+		// the descriptor was closed by OpenWithOptions
+		_, err = unix.FcntlInt(uintptr(p[0]), unix.F_GETFD, 0)
+		assert.Equal(t, syscall.EBADF, err)
+	})
+
+	t.Run("config", func(t *testing.T) {
+		c := defaultOpenConfig()
+		for _, option := range []OpenOption{WithBufferSize(8), WithReadBatchSize(16), WithOverflowPolicy(OverflowCoalesce),
+			WithLazyProbe(), WithGrab(), WithClock(CLOCK_MONOTONIC), WithEventMask(EV_KEY, []int{KEY_A})} {
+			option(&c)
+		}
+
+		assert.Equal(t, 8, c.buffersize)
+		assert.Equal(t, 16, c.batchsize)
+		assert.Equal(t, OverflowCoalesce, c.policy)
+		assert.True(t, c.lazyprobe)
+		assert.True(t, c.grab)
+		assert.True(t, c.setclock)
+		assert.Equal(t, CLOCK_MONOTONIC, c.clockid)
+		assert.Equal(t, map[int][]int{EV_KEY: {KEY_A}}, c.eventmasks)
+	})
+}
@@ -0,0 +1,284 @@
+package inputeventsubsystem
+
+import (
+	"time"
+	"unsafe"
+)
+
+const defaultReadBatchSize = 64
+
+// OverflowPolicy select what the read loop do when the consumer is slower than the device
+type OverflowPolicy int
+
+const (
+	OverflowBlock      OverflowPolicy = iota // wait the consumer, the kernel buffer may overflow (SYN_DROPPED)
+	OverflowDropOldest                       // drop the oldest queued batch
+	OverflowDropNewest                       // drop the batch just read
+	OverflowCoalesce                         // merge the pending batches, summing REL deltas and keeping the last ABS values
+)
+
+type ReadStats struct {
+	Dropped   uint64 // events dropped by the overflow policy
+	Coalesced uint64 // events merged into another one by OverflowCoalesce
+}
+
+// SetReadBatchSize set the maximum number of events read at once, it must be called before starting a read loop
+func (dev *Device) SetReadBatchSize(size int) {
+	if size <= 0 {
+		size = defaultReadBatchSize
+	}
+	dev.batchsize = size
+}
+
+func (dev *Device) ReadBatchSize() int {
+	if dev.batchsize <= 0 {
+		return defaultReadBatchSize
+	}
+	return dev.batchsize
+}
+
+// SetOverflowPolicy set the overflow policy, it must be called before starting a read loop
+func (dev *Device) SetOverflowPolicy(policy OverflowPolicy) {
+	dev.policy = policy
+}
+
+func (dev *Device) ReadStats() ReadStats {
+	return ReadStats{Dropped: dev.dropped.Load(), Coalesced: dev.coalesced.Load()}
+}
+
+func (dev *Device) getBuffer() []byte {
+	size := dev.ReadBatchSize() * deviceinputeventsize
+	if buf, ok := dev.buffers.Get().([]byte); ok && len(buf) == size {
+		return buf
+	}
+	return make([]byte, size)
+}
+
+func (dev *Device) putBuffer(buf []byte) {
+	dev.buffers.Put(buf)
+}
+
+// unsafeBufferOf find back the pooled buffer of a slice returned by UnsafeRead, even resliced
+func (dev *Device) unsafeBufferOf(events []Event) []byte {
+	// the last event of the capacity is always the last event of the buffer
+	batchsize := dev.ReadBatchSize()
+	last := &events[:cap(events)][cap(events)-1]
+	first := unsafe.Add(unsafe.Pointer(last), -deviceinputeventsize*(batchsize-1))
+	return unsafe.Slice((*byte)(first), deviceinputeventsize*batchsize)
+}
+
+// readQueue deliver the items of a read loop on its channel according to the overflow policy
+type readQueue[T any] struct {
+	dev      *Device
+	ch       chan T
+	pending  []Event
+	count    func(T) int
+	release  func(T)
+	appendTo func([]Event, T) []Event
+	build    func([]Event) T
+}
+
+func (q *readQueue[T]) push(item T) {
+	switch q.dev.policy {
+
+	case OverflowDropNewest:
+		select {
+		case q.ch <- item:
+		default:
+			q.dev.dropped.Add(uint64(q.count(item)))
+			q.release(item)
+		}
+
+	case OverflowDropOldest:
+		// an unbuffered channel has no oldest batch to drop
+		if cap(q.ch) == 0 {
+			q.ch <- item
+			return
+		}
+
+		for {
+			select {
+			case q.ch <- item:
+				return
+			default:
+			}
+
+			select {
+			case old := <-q.ch:
+				q.dev.dropped.Add(uint64(q.count(old)))
+				q.release(old)
+			default:
+			}
+		}
+
+	case OverflowCoalesce:
+		if len(q.pending) == 0 {
+			select {
+			case q.ch <- item:
+				return
+			default:
+			}
+		}
+
+		var coalesced int
+		q.pending, coalesced = CoalesceEvents(q.pending, q.appendTo(nil, item))
+		q.release(item)
+		q.dev.coalesced.Add(uint64(coalesced))
+
+		// the events that can not be merged (keys, frames of touches...) are never dropped, wait the consumer
+		for batchsize := q.dev.ReadBatchSize(); len(q.pending) > batchsize; {
+			q.ch <- q.build(q.pending[:batchsize])
+			q.pending = append(q.pending[:0], q.pending[batchsize:]...)
+		}
+		q.flush()
+
+	default:
+		q.ch <- item
+	}
+}
+
+// flush try to deliver the coalesced events without blocking
+func (q *readQueue[T]) flush() {
+	if len(q.pending) == 0 || (cap(q.ch) > 0 && len(q.ch) == cap(q.ch)) {
+		return
+	}
+
+	item := q.build(q.pending)
+	select {
+	case q.ch <- item:
+		q.pending = q.pending[:0]
+	default:
+		q.release(item)
+	}
+}
+
+// timeout of the wait for events, short when coalesced events wait for room in the channel
+func (q *readQueue[T]) timeout() time.Duration {
+	if len(q.pending) > 0 {
+		return time.Millisecond
+	}
+	return time.Second
+}
+
+func isCoalescable(ev *Event) bool {
+	return ev.Type == EV_REL || (ev.Type == EV_ABS && ev.Code < ABS_MT_SLOT)
+}
+
+// mergeWindow return the start of the events an event can be merged into: the frames after the last event that
+// can not be merged, so a merge never move a value before a key or a touch
+func mergeWindow(pending []Event) int {
+	for i := len(pending) - 1; i >= 0; i-- {
+		ev := &pending[i]
+		if !isCoalescable(ev) && !(ev.Type == EV_SYN && ev.Code == SYN_REPORT) {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// CoalesceEvents merge events in pending: REL deltas are summed and the last value of single touch ABS axes is kept
+// in the last event of the code, if no other kind of event follow it. The merged events keep their time so the
+// queue stay in time order. Consecutive SYN_REPORT collapse and the other events are appended as is. It return the merged events and the number of events merged away
+func CoalesceEvents(pending []Event, events []Event) ([]Event, int) {
+	var coalesced int
+
+	for _, ev := range events {
+
+		if ev.Type == EV_SYN && ev.Code == SYN_REPORT && len(pending) > 0 {
+			last := &pending[len(pending)-1]
+			if last.Type == EV_SYN && last.Code == SYN_REPORT {
+				last.Time = ev.Time
+				coalesced++
+				continue
+			}
+		}
+
+		merged := false
+		if isCoalescable(&ev) {
+			for i := len(pending) - 1; i >= mergeWindow(pending); i-- {
+				if pending[i].Type == ev.Type && pending[i].Code == ev.Code {
+					if ev.Type == EV_REL {
+						pending[i].Value += ev.Value
+					} else {
+						pending[i].Value = ev.Value
+					}
+					// the frame keep its time, the frames queued after it are newer
+					merged = true
+					break
+				}
+			}
+		}
+
+		if merged {
+			coalesced++
+		} else {
+			pending = append(pending, ev)
+		}
+	}
+
+	return pending, coalesced
+}
+
+func (dev *Device) eventsQueue() *readQueue[[]*Event] {
+	return &readQueue[[]*Event]{
+		dev:   dev,
+		ch:    dev.eventchan,
+		count: func(events []*Event) int { return len(events) },
+		release: func(events []*Event) {
+			dev.ReadDone(events)
+		},
+		appendTo: func(dst []Event, events []*Event) []Event {
+			for _, ev := range events {
+				dst = append(dst, *ev)
+			}
+			return dst
+		},
+		build: func(pending []Event) []*Event {
+			events := make([]*Event, len(pending))
+			for i := range pending {
+				ev := eventPool.Get().(*Event)
+				*ev = pending[i]
+				events[i] = ev
+			}
+			return events
+		},
+	}
+}
+
+func (dev *Device) unsafeEventsQueue() *readQueue[[]Event] {
+	return &readQueue[[]Event]{
+		dev:   dev,
+		ch:    dev.unsafeeventchan,
+		count: func(events []Event) int { return len(events) },
+		release: func(events []Event) {
+			dev.UnsafeReadDone(events)
+		},
+		appendTo: func(dst []Event, events []Event) []Event {
+			return append(dst, events...)
+		},
+		build: func(pending []Event) []Event {
+			buf := dev.getBuffer()
+			n := copy(eventsOf(buf), pending)
+			return eventsOf(buf[:n*deviceinputeventsize])
+		},
+	}
+}
+
+func (dev *Device) batchQueue() *readQueue[*Batch] {
+	return &readQueue[*Batch]{
+		dev:   dev,
+		ch:    dev.batchchan,
+		count: func(b *Batch) int { return len(b.events) },
+		release: func(b *Batch) {
+			b.Release()
+		},
+		appendTo: func(dst []Event, b *Batch) []Event {
+			return append(dst, b.events...)
+		},
+		build: func(pending []Event) *Batch {
+			buf := dev.getBuffer()
+			n := copy(eventsOf(buf), pending)
+			return dev.newBatch(buf, eventsOf(buf[:n*deviceinputeventsize]))
+		},
+	}
+}
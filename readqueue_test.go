@@ -0,0 +1,188 @@
+package inputeventsubsystem
+
+import (
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func relFrame(dx int32, dy int32) []Event {
+	return []Event{
+		{Type: EV_REL, Code: REL_X, Value: dx},
+		{Type: EV_REL, Code: REL_Y, Value: dy},
+		{Type: EV_SYN, Code: SYN_REPORT},
+	}
+}
+
+func TestCoalesceEvents(t *testing.T) {
+	pending, coalesced := CoalesceEvents(nil, relFrame(1, 2))
+	assert.Equal(t, 0, coalesced)
+
+	pending, coalesced = CoalesceEvents(pending, relFrame(3, -5))
+	assert.Equal(t, 3, coalesced)
+	assert.Equal(t, relFrame(4, -3), pending)
+
+	pending, coalesced = CoalesceEvents(pending, []Event{
+		{Type: EV_KEY, Code: BTN_LEFT, Value: 1},
+		{Type: EV_ABS, Code: ABS_X, Value: 10},
+		{Type: EV_ABS, Code: ABS_MT_POSITION_X, Value: 10},
+		{Type: EV_SYN, Code: SYN_REPORT},
+		{Type: EV_ABS, Code: ABS_X, Value: 20},
+		{Type: EV_ABS, Code: ABS_MT_POSITION_X, Value: 20},
+		{Type: EV_SYN, Code: SYN_REPORT},
+	})
+	// the touch events in between keep ABS_X in its frame
+	assert.Equal(t, 0, coalesced)
+	assert.Len(t, pending, 10)
+}
+
+func TestCoalesceEventsKeyOrder(t *testing.T) {
+	absFrame := func(x int32) []Event {
+		return []Event{{Type: EV_ABS, Code: ABS_X, Value: x}, {Type: EV_SYN, Code: SYN_REPORT}}
+	}
+	keyFrame := []Event{{Type: EV_KEY, Code: BTN_LEFT, Value: 1}, {Type: EV_SYN, Code: SYN_REPORT}}
+
+	pending, _ := CoalesceEvents(nil, absFrame(1))
+	pending, _ = CoalesceEvents(pending, keyFrame)
+	pending, coalesced := CoalesceEvents(pending, absFrame(2))
+
+	// ABS_X 2 stay after the key press
+	assert.Equal(t, 0, coalesced)
+	assert.Equal(t, append(append(absFrame(1), keyFrame...), absFrame(2)...), pending)
+
+	pending, coalesced = CoalesceEvents(pending, absFrame(3))
+	assert.Equal(t, 2, coalesced)
+	assert.Equal(t, append(append(absFrame(1), keyFrame...), absFrame(3)...), pending)
+}
+
+func queueDevice(policy OverflowPolicy) *Device {
+	dev := &Device{batchchan: make(chan *Batch, 1)}
+	dev.SetOverflowPolicy(policy)
+	dev.SetReadBatchSize(8)
+	return dev
+}
+
+func frameBatch(dev *Device, events []Event) *Batch {
+	buf := dev.getBuffer()
+	n := copy(eventsOf(buf), events)
+	return dev.newBatch(buf, eventsOf(buf[:n*deviceinputeventsize]))
+}
+
+func TestOverflowPolicy(t *testing.T) {
+	t.Run("drop newest", func(t *testing.T) {
+		dev := queueDevice(OverflowDropNewest)
+		q := dev.batchQueue()
+
+		q.push(frameBatch(dev, relFrame(1, 1)))
+		q.push(frameBatch(dev, relFrame(2, 2)))
+
+		b := <-dev.batchchan
+		assert.Equal(t, relFrame(1, 1), b.Events())
+		assert.Equal(t, ReadStats{Dropped: 3}, dev.ReadStats())
+	})
+
+	t.Run("drop oldest", func(t *testing.T) {
+		dev := queueDevice(OverflowDropOldest)
+		q := dev.batchQueue()
+
+		q.push(frameBatch(dev, relFrame(1, 1)))
+		q.push(frameBatch(dev, relFrame(2, 2)))
+
+		b := <-dev.batchchan
+		assert.Equal(t, relFrame(2, 2), b.Events())
+		assert.Equal(t, ReadStats{Dropped: 3}, dev.ReadStats())
+	})
+
+	t.Run("coalesce", func(t *testing.T) {
+		dev := queueDevice(OverflowCoalesce)
+		q := dev.batchQueue()
+
+		q.push(frameBatch(dev, relFrame(1, 1)))
+		q.push(frameBatch(dev, relFrame(2, 2)))
+		q.push(frameBatch(dev, relFrame(3, -1)))
+		assert.Len(t, q.pending, 3)
+
+		b := <-dev.batchchan
+		assert.Equal(t, relFrame(1, 1), b.Events())
+
+		q.flush()
+		b = <-dev.batchchan
+		assert.Equal(t, relFrame(5, 1), b.Events())
+		assert.Empty(t, q.pending)
+		assert.Equal(t, ReadStats{Coalesced: 3}, dev.ReadStats())
+	})
+
+	t.Run("coalesce overflow", func(t *testing.T) {
+		dev := queueDevice(OverflowCoalesce)
+		q := dev.batchQueue()
+
+		q.push(frameBatch(dev, relFrame(1, 1)))
+
+		// the key events can not be merged, the queue wait the consumer instead of dropping them
+		done := make(chan struct{})
+		go func() {
+			for i := 0; i < 5; i++ {
+				q.push(frameBatch(dev, []Event{{Type: EV_KEY, Code: KEY_A, Value: 1}, {Type: EV_SYN, Code: SYN_REPORT}}))
+			}
+			close(done)
+		}()
+
+		b := <-dev.batchchan
+		assert.Equal(t, relFrame(1, 1), b.Events())
+
+		var delivered []Event
+		for waiting := true; waiting; {
+			select {
+			case b := <-dev.batchchan:
+				delivered = append(delivered, b.Events()...)
+			case <-done:
+				waiting = false
+			}
+		}
+		for len(dev.batchchan) > 0 {
+			delivered = append(delivered, (<-dev.batchchan).Events()...)
+		}
+		delivered = append(delivered, q.pending...)
+
+		assert.Len(t, delivered, 10)
+		assert.Equal(t, ReadStats{}, dev.ReadStats())
+	})
+
+	t.Run("drop oldest unbuffered", func(t *testing.T) {
+		dev := queueDevice(OverflowDropOldest)
+		dev.batchchan = make(chan *Batch)
+		q := dev.batchQueue()
+
+		go q.push(frameBatch(dev, relFrame(1, 1)))
+
+		b := <-dev.batchchan
+		assert.Equal(t, relFrame(1, 1), b.Events())
+	})
+}
+
+func TestCoalesceEventsTime(t *testing.T) {
+	at := func(sec int64, ev Event) Event {
+		ev.Time = syscall.NsecToTimeval(sec * int64(time.Second))
+		return ev
+	}
+
+	pending, _ := CoalesceEvents(nil, []Event{
+		at(1, Event{Type: EV_ABS, Code: ABS_X, Value: 1}),
+		at(1, Event{Type: EV_SYN, Code: SYN_REPORT}),
+		at(2, Event{Type: EV_ABS, Code: ABS_Y, Value: 1}),
+		at(2, Event{Type: EV_SYN, Code: SYN_REPORT}),
+	})
+	pending, coalesced := CoalesceEvents(pending, []Event{
+		at(3, Event{Type: EV_ABS, Code: ABS_X, Value: 2}),
+		at(3, Event{Type: EV_SYN, Code: SYN_REPORT}),
+	})
+
+	// ABS_X 2 is merged in the first frame, the times never go back
+	assert.Equal(t, 2, coalesced)
+	assert.Equal(t, int32(2), pending[0].Value)
+	for i := 1; i < len(pending); i++ {
+		assert.LessOrEqual(t, pending[i-1].Nanoseconds(), pending[i].Nanoseconds())
+	}
+}
@@ -0,0 +1,147 @@
+package inputeventsubsystem
+
+import (
+	"syscall"
+	"time"
+)
+
+// Repeat return the kernel autorepeat delay and period of the device
+func (dev *Device) Repeat() (time.Duration, time.Duration, error) {
+	delay, period, err := dev.backend.Repeat()
+	if err != nil {
+		return 0, 0, dev.wrapError(OpIoctl, nil, err)
+	}
+	return time.Duration(delay) * time.Millisecond, time.Duration(period) * time.Millisecond, nil
+}
+
+// SetRepeat change the kernel autorepeat delay and period of the device, a period of 0 disable the autorepeat
+func (dev *Device) SetRepeat(delay time.Duration, period time.Duration) error {
+	return dev.wrapError(OpIoctl, nil, dev.backend.SetRepeat(uint32(delay.Milliseconds()), uint32(period.Milliseconds())))
+}
+
+// SoftRepeat generate autorepeat events (EV_KEY value 2) for devices whose kernel repeat is disabled.
+// Like the kernel, only the last pressed key is repeated
+type SoftRepeat struct {
+	delay  time.Duration
+	period time.Duration
+	clock  Clock
+	keys   chan Event
+	events chan []Event
+	stop   chan struct{}
+	done   chan struct{}
+	code   uint16           // key repeated, owned by run
+	timer  <-chan time.Time // next repeat, owned by run
+}
+
+func NewSoftRepeat(delay time.Duration, period time.Duration, clock Clock) *SoftRepeat {
+	if clock == nil {
+		clock = SystemClock
+	}
+
+	r := &SoftRepeat{
+		delay:  delay,
+		period: period,
+		clock:  clock,
+		keys:   make(chan Event),
+		events: make(chan []Event),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	go r.run()
+	return r
+}
+
+// Feed give the key events read from the device to the generator, the EV_REP events change the delay and
+// the period (in ms) like the kernel, other events are ignored
+func (r *SoftRepeat) Feed(ev Event) {
+	if (ev.Type != EV_KEY || ev.Value == 2) && ev.Type != EV_REP {
+		return
+	}
+
+	select {
+	case r.keys <- ev:
+	case <-r.stop:
+	}
+}
+
+// Events return the channel of generated repeat events, each batch end with a SYN_REPORT
+func (r *SoftRepeat) Events() <-chan []Event {
+	return r.events
+}
+
+func (r *SoftRepeat) Close() {
+	select {
+	case <-r.stop:
+	default:
+		close(r.stop)
+	}
+	<-r.done
+}
+
+// update apply a key or EV_REP event, it return false when the repeat in progress is stopped or restarted
+func (r *SoftRepeat) update(ev Event) bool {
+	switch {
+	case ev.Type == EV_REP:
+		switch ev.Code {
+		case REP_DELAY:
+			r.delay = time.Duration(ev.Value) * time.Millisecond
+		case REP_PERIOD:
+			r.period = time.Duration(ev.Value) * time.Millisecond
+		}
+		// the running timer end with the old value, a period of 0 stop the repeat
+		if r.delay <= 0 || r.period <= 0 {
+			r.timer = nil
+			return false
+		}
+
+	case ev.Value == 1:
+		r.code = ev.Code
+		r.timer = nil
+		if r.delay > 0 && r.period > 0 {
+			r.timer = r.clock.After(r.delay)
+		}
+		return false
+
+	case ev.Code == r.code:
+		r.timer = nil
+		return false
+	}
+	return true
+}
+
+func (r *SoftRepeat) run() {
+	defer close(r.done)
+
+	for {
+		select {
+		case ev := <-r.keys:
+			r.update(ev)
+
+		case <-r.timer:
+			tv := syscall.NsecToTimeval(r.clock.Now().UnixNano())
+			batch := []Event{
+				{Time: tv, Type: EV_KEY, Code: r.code, Value: 2},
+				{Time: tv, Type: EV_SYN, Code: SYN_REPORT, Value: 0},
+			}
+
+			// the keys are still received while the batch wait, the caller may feed and drain from one goroutine.
+			// A release or a new press drop the batch
+			r.timer = nil
+			for pending := true; pending; {
+				select {
+				case r.events <- batch:
+					r.timer = r.clock.After(r.period)
+					pending = false
+				case ev := <-r.keys:
+					pending = r.update(ev)
+				case <-r.stop:
+					return
+				}
+			}
+
+		case <-r.stop:
+			return
+		}
+	}
+}
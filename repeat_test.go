@@ -0,0 +1,99 @@
+package inputeventsubsystem
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSoftRepeat(t *testing.T) {
+	clk := newFakeClock()
+	r := NewSoftRepeat(250*time.Millisecond, 33*time.Millisecond, clk)
+	defer r.Close()
+
+	r.Feed(Event{Type: EV_KEY, Code: KEY_A, Value: 1})
+
+	w := <-clk.waiters
+	assert.Equal(t, 250*time.Millisecond, w.d)
+	clk.fire(w)
+
+	batch := <-r.Events()
+	assert.Len(t, batch, 2)
+	assert.Equal(t, Event{Time: batch[0].Time, Type: EV_KEY, Code: KEY_A, Value: 2}, batch[0])
+	assert.Equal(t, uint16(EV_SYN), batch[1].Type)
+	assert.Equal(t, clk.Now().UnixNano(), batch[0].Time.Nano())
+
+	w = <-clk.waiters
+	assert.Equal(t, 33*time.Millisecond, w.d)
+	clk.fire(w)
+	batch = <-r.Events()
+	assert.Equal(t, uint16(KEY_A), batch[0].Code)
+
+	// a second key take over the repeat
+	<-clk.waiters
+	r.Feed(Event{Type: EV_KEY, Code: KEY_B, Value: 1})
+	w = <-clk.waiters
+	assert.Equal(t, 250*time.Millisecond, w.d)
+
+	// releasing the first key does not stop the second one
+	r.Feed(Event{Type: EV_KEY, Code: KEY_A, Value: 0})
+	clk.fire(w)
+	batch = <-r.Events()
+	assert.Equal(t, uint16(KEY_B), batch[0].Code)
+
+	w = <-clk.waiters
+	r.Feed(Event{Type: EV_KEY, Code: KEY_B, Value: 0})
+	clk.fire(w)
+
+	select {
+	case <-r.Events():
+		t.Fatal("repeat after release")
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestSoftRepeatRate(t *testing.T) {
+	clk := newFakeClock()
+	r := NewSoftRepeat(250*time.Millisecond, 33*time.Millisecond, clk)
+	defer r.Close()
+
+	r.Feed(Event{Type: EV_REP, Code: REP_DELAY, Value: 500})
+	r.Feed(Event{Type: EV_REP, Code: REP_PERIOD, Value: 100})
+	r.Feed(Event{Type: EV_KEY, Code: KEY_A, Value: 1})
+
+	w := <-clk.waiters
+	assert.Equal(t, 500*time.Millisecond, w.d)
+	clk.fire(w)
+	<-r.Events()
+
+	w = <-clk.waiters
+	assert.Equal(t, 100*time.Millisecond, w.d)
+
+	// a period of 0 disable the repeat
+	r.Feed(Event{Type: EV_REP, Code: REP_PERIOD, Value: 0})
+	clk.fire(w)
+
+	select {
+	case <-r.Events():
+		t.Fatal("repeat with a period of 0")
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestSoftRepeatFeedWhileDue(t *testing.T) {
+	clk := newFakeClock()
+	r := NewSoftRepeat(250*time.Millisecond, 33*time.Millisecond, clk)
+	defer r.Close()
+
+	// the repeat is due but nobody drain Events, the release must not block
+	r.Feed(Event{Type: EV_KEY, Code: KEY_A, Value: 1})
+	clk.fire(<-clk.waiters)
+	r.Feed(Event{Type: EV_KEY, Code: KEY_A, Value: 0})
+
+	select {
+	case <-r.Events():
+		t.Fatal("repeat after release")
+	case <-time.After(10 * time.Millisecond):
+	}
+}
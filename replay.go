@@ -0,0 +1,247 @@
+package inputeventsubsystem
+
+import (
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ReplaySink receive the replayed events, one frame (events up to a SYN_REPORT) at a time.
+// UinputDevice is a sink, ReplayFunc adapt an in-process consumer
+type ReplaySink interface {
+	WriteEvents(events []Event) error
+}
+
+type ReplayFunc func(events []Event) error
+
+func (f ReplayFunc) WriteEvents(events []Event) error {
+	return f(events)
+}
+
+// Replayer play a recorded event stream into a sink, keeping, scaling or ignoring the recorded timing.
+// The events are stamped with the clock time of their delivery
+type Replayer struct {
+	frames  [][]Event
+	offsets []time.Duration // offset of each frame from the first event
+	sink    ReplaySink
+	clock   Clock
+
+	lock    sync.Mutex
+	speed   float64
+	pos     int
+	paused  bool
+	changed chan struct{} // closed and replaced on each seek, pause, resume or speed change
+	cancel  chan struct{}
+	done    chan struct{}
+}
+
+func splitFrames(events []Event) ([][]Event, []time.Duration) {
+	var frames [][]Event
+	var offsets []time.Duration
+
+	var start int64
+	if len(events) > 0 {
+		start = events[0].Nanoseconds()
+	}
+
+	begin := 0
+	for i, ev := range events {
+		if (ev.Type == EV_SYN && ev.Code == SYN_REPORT) || i == len(events)-1 {
+			frames = append(frames, events[begin:i+1])
+			offsets = append(offsets, time.Duration(ev.Nanoseconds()-start))
+			begin = i + 1
+		}
+	}
+	return frames, offsets
+}
+
+func NewReplayer(events []Event, sink ReplaySink, clock Clock) *Replayer {
+	if clock == nil {
+		clock = SystemClock
+	}
+
+	r := &Replayer{sink: sink, clock: clock, speed: 1, changed: make(chan struct{})}
+	r.frames, r.offsets = splitFrames(events)
+	return r
+}
+
+func (r *Replayer) notifyLocked() {
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
+
+// SetSpeed scale the timing: 1 keep it, 2 play twice faster, 0.5 twice slower, 0 as fast as possible
+func (r *Replayer) SetSpeed(speed float64) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if speed < 0 {
+		speed = 0
+	}
+	r.speed = speed
+	r.notifyLocked()
+}
+
+func (r *Replayer) Pause() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.paused = true
+	r.notifyLocked()
+}
+
+func (r *Replayer) Resume() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.paused = false
+	r.notifyLocked()
+}
+
+// Seek move to the first frame at or after offset, the frame is delivered without waiting
+func (r *Replayer) Seek(offset time.Duration) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.pos = len(r.frames)
+	for i, o := range r.offsets {
+		if o >= offset {
+			r.pos = i
+			break
+		}
+	}
+	r.notifyLocked()
+}
+
+// Position return the recorded offset of the next frame, Duration at the end
+func (r *Replayer) Position() time.Duration {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.pos >= len(r.frames) {
+		return r.Duration()
+	}
+	return r.offsets[r.pos]
+}
+
+// Duration of the recording, from the first to the last event
+func (r *Replayer) Duration() time.Duration {
+	if len(r.offsets) == 0 {
+		return 0
+	}
+	return r.offsets[len(r.offsets)-1]
+}
+
+// Play start the replay from the current position, a running replay is stopped first.
+// The returned channel receive nil at the end of the recording, ErrReplayStopped or the sink error
+func (r *Replayer) Play() <-chan error {
+	r.Stop()
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	cancel := make(chan struct{})
+	done := make(chan struct{})
+	result := make(chan error, 1)
+	r.cancel = cancel
+	r.done = done
+
+	go func() {
+		defer close(done)
+		result <- r.run(cancel)
+		close(result)
+	}()
+
+	return result
+}
+
+// Stop the replay and wait its end, the position is kept
+func (r *Replayer) Stop() {
+	r.lock.Lock()
+	cancel, done := r.cancel, r.done
+	r.cancel, r.done = nil, nil
+	r.lock.Unlock()
+
+	if cancel == nil {
+		return
+	}
+	close(cancel)
+	<-done
+}
+
+func (r *Replayer) run(cancel chan struct{}) error {
+
+	// offset and delivery time of the previous frame, the first frame played after a start,
+	// a seek or a pause is delivered at once
+	var previous time.Duration = -1
+	var delivered time.Time
+	next := -1
+
+	for {
+		r.lock.Lock()
+		if r.pos >= len(r.frames) {
+			r.lock.Unlock()
+			return nil
+		}
+
+		changed := r.changed
+		paused := r.paused
+		pos := r.pos
+		if pos != next {
+			previous = -1
+		}
+
+		var wait time.Duration
+		if previous >= 0 && r.speed > 0 {
+			wait = time.Duration(float64(r.offsets[pos]-previous)/r.speed) - r.clock.Now().Sub(delivered)
+		}
+		r.lock.Unlock()
+
+		if paused {
+			select {
+			case <-changed:
+				next = -1
+				continue
+			case <-cancel:
+				return ErrReplayStopped
+			}
+		}
+
+		if wait > 0 {
+			select {
+			case <-r.clock.After(wait):
+			case <-changed:
+				continue
+			case <-cancel:
+				return ErrReplayStopped
+			}
+		}
+
+		r.lock.Lock()
+		if r.pos != pos {
+			r.lock.Unlock()
+			continue
+		}
+		frame := append([]Event(nil), r.frames[pos]...)
+		r.pos++
+		r.lock.Unlock()
+
+		delivered = r.clock.Now()
+		tv := syscall.NsecToTimeval(delivered.UnixNano())
+		for i := range frame {
+			frame[i].Time = tv
+		}
+
+		if err := r.sink.WriteEvents(frame); err != nil {
+			return err
+		}
+		previous = r.offsets[pos]
+		next = pos + 1
+
+		select {
+		case <-cancel:
+			return ErrReplayStopped
+		default:
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package inputeventsubsystem
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// three frames recorded at 0, 10ms and 30ms
+func replayRecording() []Event {
+	var events []Event
+	for i, offset := range []int64{0, 10000, 30000} {
+		var ev Event
+		setTimeval(&ev.Time, 1700000000, offset)
+		ev.Type, ev.Code, ev.Value = EV_REL, REL_X, int32(i+1)
+		events = append(events, ev)
+		ev.Type, ev.Code, ev.Value = EV_SYN, SYN_REPORT, 0
+		events = append(events, ev)
+	}
+	return events
+}
+
+func replaySink() (ReplayFunc, chan []Event) {
+	frames := make(chan []Event, 16)
+	return ReplayFunc(func(events []Event) error {
+		frames <- events
+		return nil
+	}), frames
+}
+
+func TestReplayTiming(t *testing.T) {
+	sink, frames := replaySink()
+	clock := newFakeClock()
+
+	r := NewReplayer(replayRecording(), sink, clock)
+	assert.Equal(t, 30*time.Millisecond, r.Duration())
+
+	r.SetSpeed(2)
+	result := r.Play()
+
+	frame := <-frames
+	assert.Equal(t, 2, len(frame))
+	assert.Equal(t, int32(1), frame[0].Value)
+	assert.Equal(t, clock.Now().UnixNano(), frame[0].Nanoseconds())
+
+	timer := <-clock.waiters
+	assert.Equal(t, 5*time.Millisecond, timer.d)
+	clock.fire(timer)
+	assert.Equal(t, int32(2), (<-frames)[0].Value)
+
+	timer = <-clock.waiters
+	assert.Equal(t, 10*time.Millisecond, timer.d)
+	clock.fire(timer)
+	assert.Equal(t, int32(3), (<-frames)[0].Value)
+
+	assert.Nil(t, <-result)
+	assert.Equal(t, r.Duration(), r.Position())
+}
+
+func TestReplayAsFastAsPossible(t *testing.T) {
+	sink, frames := replaySink()
+
+	r := NewReplayer(replayRecording(), sink, newFakeClock())
+	r.SetSpeed(0)
+
+	assert.Nil(t, <-r.Play())
+	assert.Equal(t, 3, len(frames))
+}
+
+func TestReplaySeekAndPause(t *testing.T) {
+	sink, frames := replaySink()
+	clock := newFakeClock()
+
+	r := NewReplayer(replayRecording(), sink, clock)
+	r.Seek(5 * time.Millisecond)
+	assert.Equal(t, 10*time.Millisecond, r.Position())
+
+	r.Pause()
+	result := r.Play()
+
+	r.Resume()
+	assert.Equal(t, int32(2), (<-frames)[0].Value)
+
+	// seeking back while waiting deliver the frame at once
+	<-clock.waiters
+	r.Seek(0)
+	assert.Equal(t, int32(1), (<-frames)[0].Value)
+
+	<-clock.waiters
+	r.Stop()
+	assert.Equal(t, ErrReplayStopped, <-result)
+}
+
+func TestReplaySinkError(t *testing.T) {
+	errSink := errors.New("sink failed")
+
+	r := NewReplayer(replayRecording(), ReplayFunc(func(events []Event) error {
+		return errSink
+	}), newFakeClock())
+
+	assert.Equal(t, errSink, <-r.Play())
+	assert.Equal(t, 10*time.Millisecond, r.Position())
+}
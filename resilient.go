@@ -0,0 +1,379 @@
+package inputeventsubsystem
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// SYN_RECONNECTED is not sent by the kernel, ResilientDevice deliver it alone in a batch once the device is back
+const SYN_RECONNECTED = 0x0e
+
+const defaultReconnectInterval = 500 * time.Millisecond
+
+// DeviceIdentity identify a physical device across its disconnections, the devnode may change
+type DeviceIdentity struct {
+	Bus       uint16
+	VendorID  uint16
+	ProductID uint16
+	Name      string
+	Phys      string
+	Uniq      string
+}
+
+func (dev *Device) Identity() DeviceIdentity {
+	return DeviceIdentity{
+		Bus:       dev.bus,
+		VendorID:  dev.VendorID,
+		ProductID: dev.ProductID,
+		Name:      dev.Name,
+		Phys:      dev.Phy,
+		Uniq:      dev.Uniq,
+	}
+}
+
+// Match compare the IDs and the uniq (serial number, bluetooth address) when the device has one,
+// else the phys (the port the device is plugged in)
+func (id DeviceIdentity) Match(other DeviceIdentity) bool {
+	if id.Bus != other.Bus || id.VendorID != other.VendorID || id.ProductID != other.ProductID {
+		return false
+	}
+
+	if id.Uniq != "" {
+		return id.Uniq == other.Uniq
+	}
+
+	if id.Phys != "" {
+		return id.Phys == other.Phys
+	}
+
+	return id.Name == other.Name
+}
+
+// ResilientDevice wrap a Device and reopen it when it disappear (USB unplug, bluetooth drop...).
+// The grab, leds, repeat and event masks set through the wrapper are restored on the new device
+// and the events keep coming on the same channel, preceded by a SYN_RECONNECTED notification
+type ResilientDevice struct {
+	identity DeviceIdentity
+	clock    Clock
+	interval time.Duration
+	scan     func() []string
+	identify func(path string) (DeviceIdentity, error)
+	open     func(path string) (*Device, error)
+
+	lock       sync.Mutex
+	dev        *Device
+	grabbed    bool
+	leds       map[int]bool
+	delay      time.Duration
+	period     time.Duration
+	setrepeat  bool
+	eventmasks map[int][]int
+
+	eventchan chan []*Event
+	errorchan chan error
+	start     sync.Once
+	stop      chan struct{}
+	stopped   int32
+	done      chan struct{}
+	closeerr  error
+}
+
+// NewResilientDevice wrap dev, the matching devices are searched in inputpath and opened with options
+func NewResilientDevice(dev *Device, inputpath string, clock Clock, options ...OpenOption) *ResilientDevice {
+	if clock == nil {
+		clock = SystemClock
+	}
+
+	r := &ResilientDevice{
+		identity:   dev.Identity(),
+		clock:      clock,
+		interval:   defaultReconnectInterval,
+		dev:        dev,
+		leds:       make(map[int]bool),
+		eventmasks: make(map[int][]int),
+		eventchan:  make(chan []*Event, cap(dev.eventchan)),
+		errorchan:  make(chan error),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+
+	r.scan = func() []string {
+		return ScanInputs(inputpath)
+	}
+	// the scanned devices are identified without the options, a grab must not reach the other keyboards
+	r.identify = func(path string) (DeviceIdentity, error) {
+		dev, err := OpenWithOptions(path, WithReadOnly(), WithLazyProbe())
+		if err != nil {
+			return DeviceIdentity{}, err
+		}
+		defer dev.Close()
+		return dev.Identity(), nil
+	}
+	r.open = func(path string) (*Device, error) {
+		return OpenWithOptions(path, options...)
+	}
+
+	for evtype, codes := range dev.eventmasks {
+		r.eventmasks[evtype] = codes
+	}
+
+	return r
+}
+
+// SetReconnectInterval set the delay between two scans of the input devices while the device is gone
+func (r *ResilientDevice) SetReconnectInterval(interval time.Duration) {
+	r.interval = interval
+}
+
+func (r *ResilientDevice) Identity() DeviceIdentity {
+	return r.identity
+}
+
+// Device return the device currently opened, it change after each reconnection
+func (r *ResilientDevice) Device() *Device {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.dev
+}
+
+func (r *ResilientDevice) Grab(state bool) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if err := r.dev.Grab(state); err != nil {
+		return err
+	}
+	r.grabbed = state
+	return nil
+}
+
+func (r *ResilientDevice) SetLED(code int, on bool) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if err := r.dev.SetLED(code, on); err != nil {
+		return err
+	}
+	r.leds[code] = on
+	return nil
+}
+
+func (r *ResilientDevice) SetRepeat(delay time.Duration, period time.Duration) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if err := r.dev.SetRepeat(delay, period); err != nil {
+		return err
+	}
+	r.delay, r.period, r.setrepeat = delay, period, true
+	return nil
+}
+
+func (r *ResilientDevice) SetEventMask(evtype int, codes []int) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if err := r.dev.SetEventMask(evtype, codes); err != nil {
+		return err
+	}
+	r.eventmasks[evtype] = append([]int(nil), codes...)
+	return nil
+}
+
+// restore apply the recorded settings to a reopened device, all are tried and the first error is returned
+func (r *ResilientDevice) restore(dev *Device) error {
+	var errs []error
+
+	for evtype, codes := range r.eventmasks {
+		errs = append(errs, dev.SetEventMask(evtype, codes))
+	}
+
+	if r.setrepeat {
+		errs = append(errs, dev.SetRepeat(r.delay, r.period))
+	}
+
+	for code, on := range r.leds {
+		errs = append(errs, dev.SetLED(code, on))
+	}
+
+	if r.grabbed {
+		errs = append(errs, dev.Grab(true))
+	}
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *ResilientDevice) Error() <-chan error {
+	return r.errorchan
+}
+
+// Read start the read loop, it survive the disconnections until Close or an unrecoverable error
+func (r *ResilientDevice) Read() chan []*Event {
+	r.start.Do(func() {
+		go r.run()
+	})
+	return r.eventchan
+}
+
+func (r *ResilientDevice) ReadDone(events []*Event) {
+	r.Device().ReadDone(events)
+}
+
+func (r *ResilientDevice) Close() error {
+	if !atomic.CompareAndSwapInt32(&r.stopped, 0, 1) {
+		return nil
+	}
+	close(r.stop)
+
+	started := true
+	r.start.Do(func() {
+		started = false
+	})
+	if !started {
+		return r.dev.Close()
+	}
+
+	// the read loop close the device it is using
+	<-r.done
+	return r.closeerr
+}
+
+func (r *ResilientDevice) run() {
+	defer close(r.done)
+
+	for {
+		dev := r.Device()
+
+		err := r.forward(dev, dev.Read())
+		if err == nil {
+			r.closeerr = dev.Close()
+			return
+		}
+		dev.Close()
+
+		// a revoke not done by us is seen as ErrDeviceGone and reconnected too, the access may come back (session switch)
+		if !errors.Is(err, ErrDeviceGone) {
+			r.sendError(err)
+			return
+		}
+
+		if !r.reconnect() {
+			return
+		}
+
+		ev := eventPool.Get().(*Event)
+		*ev = Event{Time: syscall.NsecToTimeval(r.clock.Now().UnixNano()), Type: EV_SYN, Code: SYN_RECONNECTED}
+		if !r.deliver([]*Event{ev}) {
+			r.closeerr = r.Device().Close()
+			return
+		}
+	}
+}
+
+// forward the events of dev until its read loop fail, it return nil when the wrapper is closed
+func (r *ResilientDevice) forward(dev *Device, events chan []*Event) error {
+	for {
+		select {
+		case p := <-events:
+			select {
+			case r.eventchan <- p:
+			case err := <-dev.Error():
+				if !r.deliver(p) || !r.drain(events) {
+					return nil
+				}
+				return err
+			case <-r.stop:
+				dev.ReadDone(p)
+				return nil
+			}
+
+		case err := <-dev.Error():
+			if !r.drain(events) {
+				return nil
+			}
+			return err
+
+		case <-r.stop:
+			return nil
+		}
+	}
+}
+
+func (r *ResilientDevice) deliver(events []*Event) bool {
+	select {
+	case r.eventchan <- events:
+		return true
+	case <-r.stop:
+		r.Device().ReadDone(events)
+		return false
+	}
+}
+
+// drain deliver the batches queued before the read loop failed, the last key releases before an unplug are there.
+// It return false when the wrapper is closed
+func (r *ResilientDevice) drain(events chan []*Event) bool {
+	for {
+		select {
+		case p := <-events:
+			if !r.deliver(p) {
+				return false
+			}
+		default:
+			return true
+		}
+	}
+}
+
+func (r *ResilientDevice) sendError(err error) {
+	select {
+	case r.errorchan <- err:
+	case <-r.stop:
+	case <-time.After(time.Duration(100) * time.Millisecond):
+	}
+}
+
+// reconnect scan the input devices until one match the identity
+func (r *ResilientDevice) reconnect() bool {
+	for {
+		select {
+		case <-r.clock.After(r.interval):
+		case <-r.stop:
+			return false
+		}
+
+		for _, path := range r.scan() {
+			if id, err := r.identify(path); err != nil || !r.identity.Match(id) {
+				continue
+			}
+
+			dev, err := r.open(path)
+			if err != nil {
+				continue
+			}
+
+			// the node may have been reused between both opens
+			if !r.identity.Match(dev.Identity()) {
+				dev.Close()
+				continue
+			}
+
+			r.lock.Lock()
+			err = r.restore(dev)
+			r.dev = dev
+			r.lock.Unlock()
+
+			if err != nil {
+				r.sendError(err)
+			}
+			return true
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package inputeventsubsystem
+
+import (
+	"errors"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+func TestDeviceIdentityMatch(t *testing.T) {
+	pad := DeviceIdentity{Bus: 0x05, VendorID: 0x054c, ProductID: 0x09cc, Name: "Wireless Controller", Phys: "usb-1/input0", Uniq: "a0:5a:5c:00:11:22"}
+
+	other := pad
+	other.Phys = "usb-2/input0"
+	assert.True(t, pad.Match(other))
+
+	other.Uniq = "a0:5a:5c:00:11:33"
+	assert.False(t, pad.Match(other))
+
+	// without uniq the port identify the device
+	pad.Uniq, other.Uniq = "", ""
+	assert.False(t, pad.Match(other))
+	other.Phys = pad.Phys
+	assert.True(t, pad.Match(other))
+
+	other.ProductID = 0x05c4
+	assert.False(t, pad.Match(other))
+}
+
+// readablePipeDevice return a pipe device able to run a read loop
+func readablePipeDevice(tb testing.TB, id DeviceIdentity) (*Device, int) {
+	dev, w := pipeDevice(tb)
+	dev.Fn = "/nonexistent/event0"
+	dev.bus, dev.VendorID, dev.ProductID, dev.Uniq = id.Bus, id.VendorID, id.ProductID, id.Uniq
+	dev.eventchan = make(chan []*Event, 1)
+	dev.errorchan = make(chan error)
+	return dev, w
+}
+
+func TestResilientDeviceReconnect(t *testing.T) {
+	id := DeviceIdentity{Bus: 0x03, VendorID: 0x045e, ProductID: 0x028e, Uniq: "serial"}
+
+	first, w1 := readablePipeDevice(t, id)
+	stranger, _ := readablePipeDevice(t, DeviceIdentity{Bus: 0x03, VendorID: 0x046d})
+	defer stranger.Close()
+	second, w2 := readablePipeDevice(t, id)
+
+	clock := newFakeClock()
+	r := NewResilientDevice(first, "", clock)
+	r.scan = func() []string { return []string{"missing", "stranger", "second"} }
+	r.identify = func(path string) (DeviceIdentity, error) {
+		switch path {
+		case "stranger":
+			return stranger.Identity(), nil
+		case "second":
+			return second.Identity(), nil
+		}
+		return DeviceIdentity{}, syscall.ENOENT
+	}
+
+	// only the matching device is opened with the options
+	var opened []string
+	r.open = func(path string) (*Device, error) {
+		opened = append(opened, path)
+		return second, nil
+	}
+
+	events := r.Read()
+
+	unix.Write(w1, data)
+	batch := <-events
+	assert.Equal(t, len(data)/deviceinputeventsize, len(batch))
+	r.ReadDone(batch)
+
+	// the device node vanished
+	first.errorchan <- first.readError(syscall.ENODEV)
+
+	clock.fire(<-clock.waiters)
+
+	batch = <-events
+	assert.Equal(t, 1, len(batch))
+	assert.Equal(t, uint16(EV_SYN), batch[0].Type)
+	assert.Equal(t, uint16(SYN_RECONNECTED), batch[0].Code)
+	r.ReadDone(batch)
+
+	assert.Same(t, second, r.Device())
+	assert.Equal(t, []string{"second"}, opened)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&first.stopped))
+
+	unix.Write(w2, data)
+	batch = <-events
+	assert.Equal(t, len(data)/deviceinputeventsize, len(batch))
+	r.ReadDone(batch)
+
+	assert.Nil(t, r.Close())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&second.stopped))
+}
+
+func TestResilientDeviceFatalError(t *testing.T) {
+	dev, _ := readablePipeDevice(t, DeviceIdentity{})
+
+	r := NewResilientDevice(dev, "", newFakeClock())
+	r.Read()
+
+	dev.errorchan <- dev.readError(syscall.EIO)
+
+	err := <-r.Error()
+	assert.True(t, errors.Is(err, syscall.EIO))
+	r.Close()
+}
+
+func TestResilientDeviceRestore(t *testing.T) {
+	first, second := fakeXboxPad(), fakeXboxPad()
+	first.SetPhysUniq("usb-1/input0", "serial")
+	second.SetPhysUniq("usb-2/input0", "serial")
+
+	dev, err := OpenBackend("first", first)
+	assert.Nil(t, err)
+
+	clock := newFakeClock()
+	r := NewResilientDevice(dev, "", clock)
+	r.scan = func() []string { return []string{"second"} }
+	r.identify = func(path string) (DeviceIdentity, error) {
+		return DeviceIdentity{Bus: 0x03, VendorID: 0x045e, ProductID: 0x028e, Name: "Microsoft X-Box 360 pad", Phys: "usb-2/input0", Uniq: "serial"}, nil
+	}
+	r.open = func(path string) (*Device, error) {
+		return OpenBackend(path, second)
+	}
+
+	assert.Nil(t, r.Grab(true))
+	assert.Nil(t, r.SetLED(LED_NUML, true))
+	assert.Nil(t, r.SetRepeat(500*time.Millisecond, 100*time.Millisecond))
+	assert.Nil(t, r.SetEventMask(EV_KEY, []int{BTN_A, BTN_B}))
+
+	events := r.Read()
+	first.Disconnect()
+	clock.fire(<-clock.waiters)
+
+	batch := <-events
+	assert.Equal(t, uint16(SYN_RECONNECTED), batch[0].Code)
+	r.ReadDone(batch)
+
+	assert.True(t, second.Grabbed())
+
+	leds, err := second.LedState()
+	assert.Nil(t, err)
+	assert.Equal(t, codesToBits([]int{LED_NUML}, LED_MAX+1), leds)
+
+	delay, period, err := r.Device().Repeat()
+	assert.Nil(t, err)
+	assert.Equal(t, 500*time.Millisecond, delay)
+	assert.Equal(t, 100*time.Millisecond, period)
+
+	mask, err := r.Device().GetEventMask(EV_KEY)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{BTN_A, BTN_B}, mask)
+
+	// the masked keys of the new device are filtered
+	second.Inject(Event{Type: EV_KEY, Code: BTN_X, Value: 1}, Event{Type: EV_KEY, Code: BTN_A, Value: 1}, Event{Type: EV_SYN, Code: SYN_REPORT})
+	batch = <-events
+	assert.Len(t, batch, 2)
+	assert.Equal(t, uint16(BTN_A), batch[0].Code)
+	r.ReadDone(batch)
+
+	assert.Nil(t, r.Close())
+}
+
+func TestResilientDeviceDrain(t *testing.T) {
+	fake := fakeXboxPad()
+
+	dev, err := OpenBackend("fake", fake, WithReadBatchSize(2))
+	assert.Nil(t, err)
+
+	clock := newFakeClock()
+	r := NewResilientDevice(dev, "", clock)
+	r.scan = func() []string { return nil }
+
+	events := r.Read()
+	for x := int32(1); x <= 3; x++ {
+		fake.Inject(Event{Type: EV_ABS, Code: ABS_X, Value: x}, Event{Type: EV_SYN, Code: SYN_REPORT})
+	}
+
+	// the frames wait in the channels while nobody read, then the device is unplugged
+	for {
+		fake.lock.Lock()
+		read := len(fake.pending) == 0
+		fake.lock.Unlock()
+		if read {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	fake.Disconnect()
+	time.Sleep(10 * time.Millisecond)
+
+	for x := int32(1); x <= 3; x++ {
+		select {
+		case batch := <-events:
+			assert.Equal(t, x, batch[0].Value)
+			r.ReadDone(batch)
+		case <-time.After(time.Second):
+			t.Fatalf("frame %d lost", x)
+		}
+	}
+
+	<-clock.waiters
+	assert.Nil(t, r.Close())
+}
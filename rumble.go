@@ -0,0 +1,156 @@
+package inputeventsubsystem
+
+import (
+	"sync"
+	"time"
+)
+
+type RumbleStep struct {
+	Strong   uint16
+	Weak     uint16
+	Duration time.Duration
+}
+
+// RumblePattern is a timeline of rumble steps. A step with both magnitudes at 0 is a pause
+type RumblePattern []RumbleStep
+
+// RumbleRamp build a pattern that goes linearly from the first magnitudes to the last ones in steps
+func RumbleRamp(strongFrom, strongTo, weakFrom, weakTo uint16, duration time.Duration, steps int) RumblePattern {
+	if steps <= 0 {
+		return nil
+	}
+
+	var pattern RumblePattern = make(RumblePattern, steps)
+	stepduration := duration / time.Duration(steps)
+
+	for i := 0; i < steps; i++ {
+		var ratio float64 = 1
+		if steps > 1 {
+			ratio = float64(i) / float64(steps-1)
+		}
+		pattern[i] = RumbleStep{
+			Strong:   uint16(float64(strongFrom) + (float64(strongTo)-float64(strongFrom))*ratio),
+			Weak:     uint16(float64(weakFrom) + (float64(weakTo)-float64(weakFrom))*ratio),
+			Duration: stepduration,
+		}
+	}
+	return pattern
+}
+
+type RumbleDevice interface {
+	UploadRumble(effect RumbleEffect) (int16, error)
+	PlayEffect(id int16, count int32) error
+	StopEffect(id int16) error
+	EraseEffect(id int16) error
+}
+
+// RumbleSequencer play rumble patterns on a device reusing one uploaded effect slot
+type RumbleSequencer struct {
+	dev    RumbleDevice
+	clock  Clock
+	lock   sync.Mutex
+	id     int16
+	cancel chan struct{}
+	done   chan struct{}
+}
+
+func NewRumbleSequencer(dev RumbleDevice, clock Clock) *RumbleSequencer {
+	if clock == nil {
+		clock = SystemClock
+	}
+	return &RumbleSequencer{dev: dev, clock: clock, id: ffEffectNewID}
+}
+
+// Play cancel the current pattern if any and start the new one.
+// The returned channel receive nil when the pattern end, ErrRumbleCanceled or the device error
+func (s *RumbleSequencer) Play(pattern RumblePattern) <-chan error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.stopLocked()
+
+	cancel := make(chan struct{})
+	done := make(chan struct{})
+	result := make(chan error, 1)
+	s.cancel = cancel
+	s.done = done
+
+	go func() {
+		defer close(done)
+		result <- s.run(pattern, cancel)
+		close(result)
+	}()
+
+	return result
+}
+
+// Stop cancel the current pattern and wait the effect is stopped
+func (s *RumbleSequencer) Stop() {
+	s.lock.Lock()
+	s.stopLocked()
+	s.lock.Unlock()
+}
+
+// Close stop the current pattern and release the effect slot
+func (s *RumbleSequencer) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.stopLocked()
+
+	if s.id == ffEffectNewID {
+		return nil
+	}
+
+	err := s.dev.EraseEffect(s.id)
+	s.id = ffEffectNewID
+	return err
+}
+
+func (s *RumbleSequencer) stopLocked() {
+	if s.cancel == nil {
+		return
+	}
+	close(s.cancel)
+	<-s.done
+	s.cancel = nil
+	s.done = nil
+}
+
+func (s *RumbleSequencer) run(pattern RumblePattern, cancel chan struct{}) error {
+
+	for _, step := range pattern {
+
+		if step.Strong == 0 && step.Weak == 0 {
+			if err := s.halt(); err != nil {
+				return err
+			}
+		} else {
+			id, err := s.dev.UploadRumble(RumbleEffect{ID: s.id, Strong: step.Strong, Weak: step.Weak, Length: step.Duration})
+			if err != nil {
+				return err
+			}
+			s.id = id
+
+			if err := s.dev.PlayEffect(s.id, 1); err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-s.clock.After(step.Duration):
+		case <-cancel:
+			s.halt()
+			return ErrRumbleCanceled
+		}
+	}
+
+	return s.halt()
+}
+
+func (s *RumbleSequencer) halt() error {
+	if s.id == ffEffectNewID {
+		return nil
+	}
+	return s.dev.StopEffect(s.id)
+}
@@ -0,0 +1,163 @@
+package inputeventsubsystem
+
+import (
+	"encoding/binary"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeTimer struct {
+	d time.Duration
+	c chan time.Time
+}
+
+// fakeClock hand each After call to the test through waiters, the test decide when it fire
+type fakeClock struct {
+	lock    sync.Mutex
+	now     time.Time
+	waiters chan fakeTimer
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Unix(1700000000, 0), waiters: make(chan fakeTimer)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	t := fakeTimer{d: d, c: make(chan time.Time, 1)}
+	c.waiters <- t
+	return t.c
+}
+
+func (c *fakeClock) fire(t fakeTimer) {
+	c.lock.Lock()
+	c.now = c.now.Add(t.d)
+	now := c.now
+	c.lock.Unlock()
+	t.c <- now
+}
+
+type fakeRumble struct {
+	lock    sync.Mutex
+	nextID  int16
+	uploads []RumbleEffect
+	played  []int16
+	stopped []int16
+	erased  []int16
+}
+
+func (f *fakeRumble) UploadRumble(effect RumbleEffect) (int16, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.uploads = append(f.uploads, effect)
+	if effect.ID == -1 {
+		effect.ID = f.nextID
+		f.nextID++
+	}
+	return effect.ID, nil
+}
+
+func (f *fakeRumble) PlayEffect(id int16, count int32) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.played = append(f.played, id)
+	return nil
+}
+
+func (f *fakeRumble) StopEffect(id int16) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.stopped = append(f.stopped, id)
+	return nil
+}
+
+func (f *fakeRumble) EraseEffect(id int16) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.erased = append(f.erased, id)
+	return nil
+}
+
+func TestRumbleSequencer(t *testing.T) {
+	t.Run("short short long reuse one slot", func(t *testing.T) {
+		dev := &fakeRumble{nextID: 3}
+		clk := newFakeClock()
+		s := NewRumbleSequencer(dev, clk)
+
+		result := s.Play(RumblePattern{
+			{Strong: 0x8000, Duration: 100 * time.Millisecond},
+			{Duration: 50 * time.Millisecond},
+			{Strong: 0x8000, Duration: 100 * time.Millisecond},
+			{Duration: 50 * time.Millisecond},
+			{Strong: 0xffff, Weak: 0xffff, Duration: 400 * time.Millisecond},
+		})
+
+		for _, d := range []time.Duration{100, 50, 100, 50, 400} {
+			w := <-clk.waiters
+			assert.Equal(t, d*time.Millisecond, w.d)
+			clk.fire(w)
+		}
+
+		assert.Nil(t, <-result)
+		assert.Len(t, dev.uploads, 3)
+		assert.Equal(t, int16(-1), dev.uploads[0].ID)
+		assert.Equal(t, int16(3), dev.uploads[1].ID)
+		assert.Equal(t, int16(3), dev.uploads[2].ID)
+		assert.Equal(t, uint16(0xffff), dev.uploads[2].Weak)
+		assert.Equal(t, []int16{3, 3, 3}, dev.played)
+		assert.Equal(t, []int16{3, 3, 3}, dev.stopped)
+
+		assert.Nil(t, s.Close())
+		assert.Equal(t, []int16{3}, dev.erased)
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		dev := &fakeRumble{}
+		clk := newFakeClock()
+		s := NewRumbleSequencer(dev, clk)
+
+		result := s.Play(RumblePattern{
+			{Strong: 0x4000, Duration: time.Second},
+			{Strong: 0x8000, Duration: time.Second},
+		})
+
+		<-clk.waiters
+		s.Stop()
+
+		assert.Equal(t, ErrRumbleCanceled, <-result)
+		assert.Len(t, dev.uploads, 1)
+		assert.Equal(t, []int16{0}, dev.stopped)
+		assert.Empty(t, dev.erased)
+	})
+
+	t.Run("ramp", func(t *testing.T) {
+		p := RumbleRamp(0, 0xffff, 0xffff, 0, time.Second, 5)
+
+		assert.Len(t, p, 5)
+		assert.Equal(t, uint16(0), p[0].Strong)
+		assert.Equal(t, uint16(0xffff), p[0].Weak)
+		assert.Equal(t, uint16(0xffff), p[4].Strong)
+		assert.Equal(t, uint16(0), p[4].Weak)
+		assert.Equal(t, 200*time.Millisecond, p[2].Duration)
+	})
+}
+
+func TestRumbleEffectPack(t *testing.T) {
+	r := RumbleEffect{ID: -1, Strong: 0x1234, Weak: 0xabcd, Length: 300 * time.Millisecond}
+	data := r.Pack()
+
+	assert.Len(t, data, FF_EFFECT_SIZE)
+	assert.Equal(t, uint16(FF_RUMBLE), binary.NativeEndian.Uint16(data[0:]))
+	assert.Equal(t, uint16(0xffff), binary.NativeEndian.Uint16(data[2:]))
+	assert.Equal(t, uint16(300), binary.NativeEndian.Uint16(data[10:]))
+	assert.Equal(t, uint16(0x1234), binary.NativeEndian.Uint16(data[16:]))
+	assert.Equal(t, uint16(0xabcd), binary.NativeEndian.Uint16(data[18:]))
+}
@@ -0,0 +1,404 @@
+package inputeventsubsystem
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrSDLMapping         = errors.New("invalid SDL mapping")
+	ErrSDLMappingNotFound = errors.New("no SDL mapping for the device")
+)
+
+// SDLGUID is the joystick GUID of SDL: bus, crc16 of the name, vendor, product and version, little endian
+type SDLGUID [16]byte
+
+// sdlCRC16 is SDL_crc16 (CRC-16/ARC)
+func sdlCRC16(data string) uint16 {
+	var crc uint16
+
+	for i := 0; i < len(data); i++ {
+		crc ^= uint16(data[i])
+		for bit := 0; bit < 8; bit++ {
+			if crc&1 != 0 {
+				crc = (crc >> 1) ^ 0xa001
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return crc
+}
+
+// NewSDLGUID compute the GUID like SDL_CreateJoystickGUID, without vendor and product the name is stored instead
+func NewSDLGUID(bus uint16, vendor uint16, product uint16, version uint16, name string) SDLGUID {
+	var guid SDLGUID
+
+	binary.LittleEndian.PutUint16(guid[0:], bus)
+	binary.LittleEndian.PutUint16(guid[2:], sdlCRC16(name))
+
+	if vendor != 0 && product != 0 {
+		binary.LittleEndian.PutUint16(guid[4:], vendor)
+		binary.LittleEndian.PutUint16(guid[8:], product)
+		binary.LittleEndian.PutUint16(guid[12:], version)
+	} else {
+		// strlcpy in the 12 remaining bytes
+		copy(guid[4:15], name)
+	}
+	return guid
+}
+
+func ParseSDLGUID(s string) (SDLGUID, error) {
+	var guid SDLGUID
+
+	if len(s) != 2*len(guid) {
+		return guid, fmt.Errorf("%w: guid %q", ErrSDLMapping, s)
+	}
+	if _, err := hex.Decode(guid[:], []byte(s)); err != nil {
+		return guid, fmt.Errorf("%w: guid %q", ErrSDLMapping, s)
+	}
+	return guid, nil
+}
+
+func (guid SDLGUID) String() string {
+	return hex.EncodeToString(guid[:])
+}
+
+func (guid SDLGUID) hasVersion() bool {
+	return binary.LittleEndian.Uint16(guid[4:]) != 0 && binary.LittleEndian.Uint16(guid[8:]) != 0
+}
+
+func (guid SDLGUID) withoutCRC() SDLGUID {
+	guid[2], guid[3] = 0, 0
+	return guid
+}
+
+func (guid SDLGUID) withoutVersion() SDLGUID {
+	if guid.hasVersion() {
+		guid[12], guid[13] = 0, 0
+	}
+	return guid
+}
+
+// SDLGUID return the GUID of the device as computed by the SDL linux joystick driver
+func (dev *Device) SDLGUID() SDLGUID {
+	return NewSDLGUID(dev.bus, dev.VendorID, dev.ProductID, dev.Version, dev.Name)
+}
+
+// SDLInput is the joystick side of a binding: button bN, axis aN (+aN and -aN for the halves, aN~ inverted)
+// or hat hN.M (M is the direction mask, 1 up, 2 right, 4 down, 8 left)
+type SDLInput struct {
+	Kind    byte // 'b', 'a' or 'h'
+	Index   int
+	HatMask int
+	Half    int // 1 the positive half of the axis, -1 the negative one, 0 the whole axis
+	Invert  bool
+}
+
+// SDLBinding bind an input to a button or an axis of the standard layout, Half is the output half of a stick (+leftx, -leftx)
+type SDLBinding struct {
+	Input  SDLInput
+	IsAxis bool
+	Button GamepadButton
+	Axis   GamepadAxis
+	Half   int
+}
+
+// SDLMapping is a line of gamecontrollerdb.txt
+type SDLMapping struct {
+	GUID     SDLGUID
+	Name     string
+	Platform string
+	Bindings []SDLBinding
+}
+
+func parseSDLInput(s string) (SDLInput, error) {
+	var in SDLInput
+
+	switch {
+	case strings.HasPrefix(s, "+"):
+		in.Half, s = 1, s[1:]
+	case strings.HasPrefix(s, "-"):
+		in.Half, s = -1, s[1:]
+	}
+
+	if strings.HasSuffix(s, "~") {
+		in.Invert, s = true, s[:len(s)-1]
+	}
+
+	if len(s) < 2 {
+		return in, fmt.Errorf("%w: input %q", ErrSDLMapping, s)
+	}
+	in.Kind = s[0]
+
+	var err error
+	switch in.Kind {
+	case 'b', 'a':
+		in.Index, err = strconv.Atoi(s[1:])
+	case 'h':
+		hat, mask, found := strings.Cut(s[1:], ".")
+		if !found {
+			return in, fmt.Errorf("%w: hat %q", ErrSDLMapping, s)
+		}
+		if in.Index, err = strconv.Atoi(hat); err == nil {
+			in.HatMask, err = strconv.Atoi(mask)
+		}
+	default:
+		return in, fmt.Errorf("%w: input %q", ErrSDLMapping, s)
+	}
+
+	if err != nil || in.Index < 0 {
+		return in, fmt.Errorf("%w: input %q", ErrSDLMapping, s)
+	}
+	if in.Kind != 'a' && (in.Half != 0 || in.Invert) {
+		return in, fmt.Errorf("%w: modifier on %q", ErrSDLMapping, s)
+	}
+	return in, nil
+}
+
+// ParseSDLMapping parse a mapping string: guid,name,a:b0,b:b1,leftx:a0,dpup:h0.1,...,platform:Linux,
+// the outputs without equivalent in the standard layout (misc1, paddles, touchpad) are ignored
+func ParseSDLMapping(line string) (SDLMapping, error) {
+	var m SDLMapping
+	var err error
+
+	fields := strings.Split(strings.TrimSpace(line), ",")
+	if len(fields) < 2 {
+		return m, fmt.Errorf("%w: %q", ErrSDLMapping, line)
+	}
+
+	if m.GUID, err = ParseSDLGUID(fields[0]); err != nil {
+		return m, err
+	}
+	m.Name = fields[1]
+
+	for _, field := range fields[2:] {
+		if field == "" {
+			continue
+		}
+
+		key, value, found := strings.Cut(field, ":")
+		if !found {
+			return m, fmt.Errorf("%w: %q", ErrSDLMapping, field)
+		}
+
+		var b SDLBinding
+		switch {
+		case strings.HasPrefix(key, "+"):
+			b.Half, key = 1, key[1:]
+		case strings.HasPrefix(key, "-"):
+			b.Half, key = -1, key[1:]
+		}
+
+		if key == "platform" {
+			m.Platform = value
+			continue
+		}
+
+		if button, ok := gamepadButtonNames[key]; ok {
+			b.Button = button
+		} else if axis, ok := gamepadAxisNames[key]; ok {
+			b.IsAxis, b.Axis = true, axis
+		} else {
+			continue
+		}
+
+		if b.Half != 0 && !b.IsAxis {
+			return m, fmt.Errorf("%w: half of button %q", ErrSDLMapping, field)
+		}
+
+		if b.Input, err = parseSDLInput(value); err != nil {
+			return m, err
+		}
+		m.Bindings = append(m.Bindings, b)
+	}
+
+	return m, nil
+}
+
+var (
+	gamepadButtonNames = make(map[string]GamepadButton)
+	gamepadAxisNames   = make(map[string]GamepadAxis)
+)
+
+func init() {
+	for button, name := range gamepadButtonString {
+		gamepadButtonNames[name] = button
+	}
+	for axis, name := range gamepadAxisString {
+		gamepadAxisNames[name] = axis
+	}
+}
+
+// SDLMappingDB hold the linux mappings of a gamecontrollerdb.txt by GUID
+type SDLMappingDB struct {
+	mappings map[SDLGUID]SDLMapping
+}
+
+func NewSDLMappingDB() *SDLMappingDB {
+	return &SDLMappingDB{mappings: make(map[SDLGUID]SDLMapping)}
+}
+
+// LoadSDLMappings read a gamecontrollerdb.txt, the mappings of the other platforms are skipped
+func LoadSDLMappings(r io.Reader) (*SDLMappingDB, error) {
+	db := NewSDLMappingDB()
+	scanner := bufio.NewScanner(r)
+
+	var line int
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		m, err := ParseSDLMapping(text)
+		if err != nil {
+			return db, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		if m.Platform == "" || m.Platform == "Linux" {
+			db.Add(m)
+		}
+	}
+
+	return db, scanner.Err()
+}
+
+// Add a mapping, it replace the mapping of the same GUID like SDL_GameControllerAddMapping
+func (db *SDLMappingDB) Add(m SDLMapping) {
+	db.mappings[m.GUID] = m
+}
+
+func (db *SDLMappingDB) Len() int {
+	return len(db.mappings)
+}
+
+// Lookup find the mapping of the GUID like SDL: exact match, then without the crc, then without the version
+func (db *SDLMappingDB) Lookup(guid SDLGUID) (SDLMapping, bool) {
+	for _, candidate := range []SDLGUID{guid, guid.withoutCRC(), guid.withoutCRC().withoutVersion()} {
+		if m, ok := db.mappings[candidate]; ok {
+			return m, true
+		}
+	}
+	return SDLMapping{}, false
+}
+
+// sdlInputs number the buttons, axes and hats of the device like the SDL linux joystick driver
+type sdlInputs struct {
+	buttons []int
+	axes    []int
+	hats    []int // ABS_HAT0X of each hat, the Y axis follow
+}
+
+func newSDLInputs(dev *Device) sdlInputs {
+	var in sdlInputs
+
+	for code := BTN_JOYSTICK; code < KEY_MAX; code++ {
+		if _, ok := dev.Capabilities[EV_KEY][code]; ok {
+			in.buttons = append(in.buttons, code)
+		}
+	}
+	for code := 0; code < BTN_JOYSTICK; code++ {
+		if _, ok := dev.Capabilities[EV_KEY][code]; ok {
+			in.buttons = append(in.buttons, code)
+		}
+	}
+
+	for code := 0; code < ABS_MAX; code++ {
+		if code == ABS_HAT0X {
+			code = ABS_HAT3Y
+			continue
+		}
+		if _, ok := dev.Capabilities[EV_ABS][code]; ok {
+			in.axes = append(in.axes, code)
+		}
+	}
+
+	for code := ABS_HAT0X; code <= ABS_HAT3Y; code += 2 {
+		_, x := dev.Capabilities[EV_ABS][code]
+		_, y := dev.Capabilities[EV_ABS][code+1]
+		if x || y {
+			in.hats = append(in.hats, code)
+		}
+	}
+	return in
+}
+
+// NewSDLGamepad build the standard layout of the device from a SDL mapping
+func NewSDLGamepad(axis *AxisDevice, mapping SDLMapping) (*Gamepad, error) {
+	in := newSDLInputs(axis.Device)
+	g := newGamepad(axis, GamepadMapping{Name: mapping.Name})
+
+	for _, m := range mapping.Bindings {
+		b := gamepadBinding{isAxis: m.IsAxis, button: m.Button, axis: m.Axis, sign: m.Half}
+
+		switch m.Input.Kind {
+		case 'b':
+			if m.Input.Index >= len(in.buttons) {
+				return nil, fmt.Errorf("%w: no button %d", ErrSDLMapping, m.Input.Index)
+			}
+			code := in.buttons[m.Input.Index]
+			g.keys[code] = append(g.keys[code], b)
+
+		case 'a':
+			if m.Input.Index >= len(in.axes) {
+				return nil, fmt.Errorf("%w: no axis %d", ErrSDLMapping, m.Input.Index)
+			}
+			code := in.axes[m.Input.Index]
+			b.half, b.invert = m.Input.Half, m.Input.Invert
+			g.abs[code] = append(g.abs[code], b)
+
+		case 'h':
+			if m.Input.Index >= len(in.hats) {
+				return nil, fmt.Errorf("%w: no hat %d", ErrSDLMapping, m.Input.Index)
+			}
+			code := in.hats[m.Input.Index]
+
+			// a hat direction is a half of one of its axes
+			switch m.Input.HatMask {
+			case 1:
+				b.half = -1
+				code++
+			case 2:
+				b.half = 1
+			case 4:
+				b.half = 1
+				code++
+			case 8:
+				b.half = -1
+			default:
+				return nil, fmt.Errorf("%w: hat mask %d", ErrSDLMapping, m.Input.HatMask)
+			}
+			g.abs[code] = append(g.abs[code], b)
+		}
+	}
+
+	return g, nil
+}
+
+// SDLGamepad open the standard layout of the device with its mapping in db
+func (e *Device) SDLGamepad(db *SDLMappingDB) (*Gamepad, error) {
+	mapping, ok := db.Lookup(e.SDLGUID())
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrSDLMappingNotFound, e.SDLGUID())
+	}
+
+	axis, err := e.gamepadAxisDevice()
+	if err != nil {
+		return nil, err
+	}
+
+	g, err := NewSDLGamepad(axis, mapping)
+	if err != nil {
+		return nil, err
+	}
+	return g, g.Sync()
+}
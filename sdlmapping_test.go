@@ -0,0 +1,170 @@
+package inputeventsubsystem
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const sdlSample = `# Game Controller DB for SDL
+030000005e0400008e02000010010000,Xbox 360 Controller,a:b0,b:b1,back:b6,dpdown:h0.4,dpleft:h0.8,dpright:h0.2,dpup:h0.1,guide:b8,leftshoulder:b4,leftstick:b9,lefttrigger:a2,leftx:a0,lefty:a1,rightshoulder:b5,rightstick:b10,righttrigger:a5,rightx:a3,righty:a4,start:b7,x:b2,y:b3,platform:Linux,
+030000005e0400008e02000000000000,Xbox 360 Controller,a:b0,b:b1,x:b2,y:b3,platform:Windows,
+
+03000000790000000600000010010000,Generic USB Joystick,a:b2,b:b1,x:b3,y:b0,misc1:b12,leftx:a0,lefty:a1~,-rightx:b4,+rightx:b5,lefttrigger:+a2,righttrigger:-a2,dpup:-a3,platform:Linux,
+`
+
+func TestSDLGUID(t *testing.T) {
+	assert.Equal(t, uint16(0xbb3d), sdlCRC16("123456789"))
+
+	guid := NewSDLGUID(0x03, 0x045e, 0x028e, 0x0110, "Microsoft X-Box 360 pad")
+	assert.Equal(t, "030000005e0400008e02000010010000", guid.withoutCRC().String())
+
+	parsed, err := ParseSDLGUID(guid.String())
+	assert.Nil(t, err)
+	assert.Equal(t, guid, parsed)
+
+	// without vendor and product the name is stored
+	guid = NewSDLGUID(0x05, 0, 0, 0, "A very long joystick name")
+	assert.Equal(t, "A very long", string(guid[4:15]))
+	assert.Equal(t, byte(0), guid[15])
+
+	_, err = ParseSDLGUID("0300")
+	assert.True(t, errors.Is(err, ErrSDLMapping))
+}
+
+func TestParseSDLMapping(t *testing.T) {
+	m, err := ParseSDLMapping("03000000790000000600000010010000,Generic,a:b2,lefty:a1~,-rightx:b4,lefttrigger:+a2,dpup:h0.1,paddle1:b20,platform:Linux,")
+	assert.Nil(t, err)
+	assert.Equal(t, "Generic", m.Name)
+	assert.Equal(t, "Linux", m.Platform)
+	assert.Equal(t, []SDLBinding{
+		{Input: SDLInput{Kind: 'b', Index: 2}, Button: GamepadButtonA},
+		{Input: SDLInput{Kind: 'a', Index: 1, Invert: true}, IsAxis: true, Axis: GamepadAxisLeftY},
+		{Input: SDLInput{Kind: 'b', Index: 4}, IsAxis: true, Axis: GamepadAxisRightX, Half: -1},
+		{Input: SDLInput{Kind: 'a', Index: 2, Half: 1}, IsAxis: true, Axis: GamepadAxisLeftTrigger},
+		{Input: SDLInput{Kind: 'h', Index: 0, HatMask: 1}, Button: GamepadButtonDpadUp},
+	}, m.Bindings)
+
+	for _, line := range []string{
+		"03000000790000000600000010010000",
+		"030000007900,Short guid,a:b0,",
+		"03000000790000000600000010010000,Bad input,a:c0,",
+		"03000000790000000600000010010000,Bad hat,a:h0,",
+		"03000000790000000600000010010000,Half button,+a:b0,",
+		"03000000790000000600000010010000,Missing value,a,",
+	} {
+		_, err := ParseSDLMapping(line)
+		assert.True(t, errors.Is(err, ErrSDLMapping), line)
+	}
+}
+
+func TestSDLMappingDB(t *testing.T) {
+	db, err := LoadSDLMappings(strings.NewReader(sdlSample))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, db.Len())
+
+	// the crc of the name and then the version are ignored when there is no exact match
+	m, ok := db.Lookup(NewSDLGUID(0x03, 0x045e, 0x028e, 0x0110, "Microsoft X-Box 360 pad"))
+	assert.True(t, ok)
+	assert.Equal(t, "Xbox 360 Controller", m.Name)
+
+	_, ok = db.Lookup(NewSDLGUID(0x03, 0x0079, 0x0006, 0x0111, "Generic"))
+	assert.False(t, ok)
+	db.Add(SDLMapping{GUID: NewSDLGUID(0x03, 0x0079, 0x0006, 0, "")})
+	_, ok = db.Lookup(NewSDLGUID(0x03, 0x0079, 0x0006, 0x0111, "Generic"))
+	assert.True(t, ok)
+
+	_, err = LoadSDLMappings(strings.NewReader("# comment\n\nnot a mapping\n"))
+	assert.True(t, errors.Is(err, ErrSDLMapping))
+	assert.Contains(t, err.Error(), "line 3")
+}
+
+func TestSDLGamepad(t *testing.T) {
+	db, _ := LoadSDLMappings(strings.NewReader(sdlSample))
+
+	dev, err := OpenBackend("fake", fakeXboxPad())
+	assert.Nil(t, err)
+	defer dev.Close()
+
+	g, err := dev.SDLGamepad(db)
+	assert.Nil(t, err)
+	assert.Equal(t, "Xbox 360 Controller", g.Mapping.Name)
+
+	for _, ev := range []Event{
+		{Type: EV_KEY, Code: BTN_Y, Value: 1},
+		{Type: EV_KEY, Code: BTN_MODE, Value: 1},
+		{Type: EV_ABS, Code: ABS_Z, Value: 255},
+		{Type: EV_ABS, Code: ABS_RY, Value: 32767},
+		{Type: EV_ABS, Code: ABS_HAT0X, Value: -1},
+		{Type: EV_SYN, Code: SYN_REPORT},
+	} {
+		g.Update(&ev)
+	}
+
+	state := g.State()
+	assert.True(t, state.Buttons[GamepadButtonY])
+	assert.True(t, state.Buttons[GamepadButtonGuide])
+	assert.True(t, state.Buttons[GamepadButtonDpadLeft])
+	assert.False(t, state.Buttons[GamepadButtonDpadRight])
+	assert.InDelta(t, 1, state.Axes[GamepadAxisLeftTrigger], 0.001)
+	assert.InDelta(t, 1, state.Axes[GamepadAxisRightY], 0.01)
+
+	dev2, _ := OpenBackend("fake", NewFakeDevice(EvemuDevice{Name: "unknown", VendorID: 1, ProductID: 2}))
+	defer dev2.Close()
+	_, err = dev2.SDLGamepad(db)
+	assert.True(t, errors.Is(err, ErrSDLMappingNotFound))
+}
+
+func TestSDLGamepadModifiers(t *testing.T) {
+	stick := AbsInfo{Minimum: -32768, Maximum: 32767}
+	fake := NewFakeDevice(EvemuDevice{
+		Name:      "Generic USB Joystick",
+		Bus:       0x03,
+		VendorID:  0x0079,
+		ProductID: 0x0006,
+		Version:   0x0110,
+		Codes: map[int][]int{
+			EV_SYN: {SYN_REPORT},
+			EV_KEY: {BTN_TRIGGER, BTN_THUMB, BTN_THUMB2, BTN_TOP, BTN_TOP2, BTN_PINKIE},
+			EV_ABS: {ABS_X, ABS_Y, ABS_Z, ABS_RZ},
+		},
+		Absinfos: map[int]AbsInfo{ABS_X: stick, ABS_Y: stick, ABS_Z: stick, ABS_RZ: stick},
+	})
+
+	dev, err := OpenBackend("fake", fake)
+	assert.Nil(t, err)
+	defer dev.Close()
+
+	db, _ := LoadSDLMappings(strings.NewReader(sdlSample))
+	g, err := dev.SDLGamepad(db)
+	assert.Nil(t, err)
+
+	update := func(events ...Event) GamepadState {
+		for _, ev := range append(events, Event{Type: EV_SYN, Code: SYN_REPORT}) {
+			g.Update(&ev)
+		}
+		return g.State()
+	}
+
+	// b0 is BTN_TRIGGER, the first button from BTN_JOYSTICK
+	state := update(Event{Type: EV_KEY, Code: BTN_TRIGGER, Value: 1}, Event{Type: EV_KEY, Code: BTN_TOP2, Value: 1})
+	assert.True(t, state.Buttons[GamepadButtonY])
+	assert.Equal(t, float64(-1), state.Axes[GamepadAxisRightX])
+
+	// inverted axis
+	state = update(Event{Type: EV_ABS, Code: ABS_Y, Value: 32767})
+	assert.InDelta(t, -1, state.Axes[GamepadAxisLeftY], 0.01)
+
+	// both triggers on the two halves of a2, a3 negative half as a button
+	state = update(Event{Type: EV_ABS, Code: ABS_Z, Value: -32768}, Event{Type: EV_ABS, Code: ABS_RZ, Value: -32768})
+	assert.InDelta(t, 0, state.Axes[GamepadAxisLeftTrigger], 0.01)
+	assert.InDelta(t, 1, state.Axes[GamepadAxisRightTrigger], 0.01)
+	assert.True(t, state.Buttons[GamepadButtonDpadUp])
+
+	state = update(Event{Type: EV_ABS, Code: ABS_Z, Value: 32767}, Event{Type: EV_ABS, Code: ABS_RZ, Value: 0})
+	assert.InDelta(t, 1, state.Axes[GamepadAxisLeftTrigger], 0.01)
+	assert.InDelta(t, 0, state.Axes[GamepadAxisRightTrigger], 0.01)
+	assert.False(t, state.Buttons[GamepadButtonDpadUp])
+}
@@ -0,0 +1,128 @@
+package inputeventsubsystem
+
+import (
+	"encoding/binary"
+	"sort"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const UinputPath = "/dev/uinput"
+
+// bit ioctl of each event type
+var uinputSetBit = map[int]uintptr{
+	EV_KEY: UI_SET_KEYBIT,
+	EV_REL: UI_SET_RELBIT,
+	EV_ABS: UI_SET_ABSBIT,
+	EV_MSC: UI_SET_MSCBIT,
+	EV_LED: UI_SET_LEDBIT,
+	EV_SND: UI_SET_SNDBIT,
+	EV_FF:  UI_SET_FFBIT,
+	EV_SW:  UI_SET_SWBIT,
+}
+
+// UinputDevice is a virtual input device created through uinput, the events written are delivered
+// by the kernel to the readers of its evdev node
+type UinputDevice struct {
+	Name string
+	fd   int
+}
+
+func packUinputSetup(desc EvemuDevice) []byte {
+	var setup []byte = make([]byte, sizeofUinputSetup)
+
+	binary.NativeEndian.PutUint16(setup[0:], desc.Bus)
+	binary.NativeEndian.PutUint16(setup[2:], desc.VendorID)
+	binary.NativeEndian.PutUint16(setup[4:], desc.ProductID)
+	binary.NativeEndian.PutUint16(setup[6:], desc.Version)
+	copy(setup[sizeofInputID:sizeofInputID+UINPUT_MAX_NAME_SIZE-1], desc.Name)
+	return setup
+}
+
+func packUinputAbsSetup(code int, a AbsInfo) []byte {
+	var abssetup []byte = make([]byte, sizeofUinputAbs)
+
+	binary.NativeEndian.PutUint16(abssetup[0:], uint16(code))
+	copy(abssetup[4:], a.Pack())
+	return abssetup
+}
+
+// CreateUinputDevice create a virtual device with the metadata and the capabilities of desc
+func CreateUinputDevice(desc EvemuDevice) (*UinputDevice, error) {
+
+	fd, err := unix.Open(UinputPath, syscall.O_CLOEXEC|syscall.O_WRONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, &DeviceError{Op: OpOpen, Path: UinputPath, Err: err}
+	}
+
+	if err = setupUinputDevice(fd, desc); err != nil {
+		syscall.Close(fd)
+		return nil, &DeviceError{Op: OpIoctl, Path: UinputPath, Err: err}
+	}
+
+	return &UinputDevice{Name: desc.Name, fd: fd}, nil
+}
+
+func setupUinputDevice(fd int, desc EvemuDevice) error {
+
+	var types []int
+	for evtype := range desc.Codes {
+		types = append(types, evtype)
+	}
+	sort.Ints(types)
+
+	for _, evtype := range types {
+		if err := IoctlUinputSetBit(fd, UI_SET_EVBIT, evtype); err != nil {
+			return err
+		}
+
+		request, ok := uinputSetBit[evtype]
+		if !ok {
+			continue
+		}
+		for _, code := range desc.Codes[evtype] {
+			if err := IoctlUinputSetBit(fd, request, code); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, prop := range desc.Properties {
+		if err := IoctlUinputSetBit(fd, UI_SET_PROPBIT, prop); err != nil {
+			return err
+		}
+	}
+
+	for code, a := range desc.Absinfos {
+		if err := IoctlUinputAbsSetup(fd, packUinputAbsSetup(code, a)); err != nil {
+			return err
+		}
+	}
+
+	if err := IoctlUinputSetup(fd, packUinputSetup(desc)); err != nil {
+		return err
+	}
+
+	return IoctlUinputCreate(fd)
+}
+
+// WriteEvents inject the events, the kernel timestamp them on delivery
+func (u *UinputDevice) WriteEvents(events []Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	data := unsafe.Slice((*byte)(unsafe.Pointer(&events[0])), len(events)*deviceinputeventsize)
+	if _, err := unix.Write(u.fd, data); err != nil {
+		return &DeviceError{Op: OpWrite, Path: UinputPath, Err: err}
+	}
+	return nil
+}
+
+// Close destroy the virtual device
+func (u *UinputDevice) Close() error {
+	IoctlUinputDestroy(u.fd)
+	return syscall.Close(u.fd)
+}
@@ -0,0 +1,22 @@
+package inputeventsubsystem
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUinputSetupPack(t *testing.T) {
+	setup := packUinputSetup(EvemuDevice{Name: "replay pad", Bus: 0x03, VendorID: 0x045e, ProductID: 0x028e, Version: 0x0114})
+	assert.Equal(t, 92, len(setup))
+	assert.Equal(t, uint16(0x045e), binary.NativeEndian.Uint16(setup[2:]))
+	assert.Equal(t, "replay pad", string(setup[8:18]))
+	assert.Equal(t, byte(0), setup[18])
+
+	abssetup := packUinputAbsSetup(ABS_RX, AbsInfo{Minimum: -32768, Maximum: 32767, Flat: 128})
+	assert.Equal(t, 28, len(abssetup))
+	assert.Equal(t, uint16(ABS_RX), binary.NativeEndian.Uint16(abssetup[0:]))
+	assert.Equal(t, uint32(0xffff8000), binary.NativeEndian.Uint32(abssetup[8:]))
+	assert.Equal(t, uint32(128), binary.NativeEndian.Uint32(abssetup[20:]))
+}